@@ -1,13 +1,60 @@
 package main
 
 import (
+	"context"
 	"docker-compose-watcher/internal/business"
+	"docker-compose-watcher/pkg/dockercompose"
+	"docker-compose-watcher/pkg/dockercompose/service"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/urfave/cli/v2"
 )
 
-const fileFlagName = "file"
+const (
+	fileFlagName         = "file"
+	backendFlagName      = "backend"
+	ignoreFlagName       = "ignore"
+	dockerignoreFlagName = "dockerignore"
+	noInferFlagName      = "no-infer"
+	debounceFlagName     = "debounce"
+	downOnExitFlagName   = "down-on-exit"
+	detachFlagName       = "detach"
+	envFileFlagName      = "env-file"
+	profileFlagName      = "profile"
+)
+
+// Backend flag values
+const (
+	backendAuto = "auto"
+	backendV1   = "v1"
+	backendV2   = "v2"
+)
+
+func parseBackend(v string) (dockercompose.Backend, error) {
+	switch v {
+	case backendAuto:
+		return dockercompose.DetectBackend()
+	case backendV1:
+		return dockercompose.BackendV1, nil
+	case backendV2:
+		return dockercompose.BackendV2, nil
+	default:
+		return 0, fmt.Errorf("invalid backend '%s', must be one of %s, %s or %s", v, backendAuto, backendV1, backendV2)
+	}
+}
+
+// discoverFiles returns the compose files of the project of the working
+// directory, like Docker Compose does when no file is given.
+func discoverFiles() ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return service.DiscoverFiles(wd)
+}
 
 func main() {
 	app := &cli.App{
@@ -23,22 +70,102 @@ func main() {
 		Flags: []cli.Flag{
 			&cli.StringSliceFlag{
 				Name:    fileFlagName,
-				Value:   cli.NewStringSlice("docker-compose.yml"),
 				Aliases: []string{"f"},
-				Usage:   "Path to the Docker Compose file",
+				Usage:   "Path to the Docker Compose file, instead of the files of COMPOSE_FILE or the compose.yaml (or docker-compose.yml) and its override file of the current or a parent directory",
+			},
+			&cli.StringFlag{
+				Name:  envFileFlagName,
+				Usage: "Path to the file of the variables that are interpolated in the Docker Compose files, instead of the .env file of the project",
+			},
+			&cli.StringSliceFlag{
+				Name:    profileFlagName,
+				EnvVars: []string{"COMPOSE_PROFILES"},
+				Usage:   "Profile of the services to run and watch, in addition to the services without profiles",
+			},
+			&cli.StringFlag{
+				Name:  backendFlagName,
+				Value: backendAuto,
+				Usage: "Docker Compose implementation to use: 'auto', 'v1' (docker-compose) or 'v2' (docker compose)",
+			},
+			&cli.StringSliceFlag{
+				Name:  ignoreFlagName,
+				Usage: "Gitignore-style pattern of paths to ignore in all source directories",
+			},
+			&cli.BoolFlag{
+				Name:  dockerignoreFlagName,
+				Usage: "Ignore the paths excluded by the .dockerignore of the services' build contexts",
+			},
+			&cli.BoolFlag{
+				Name:  noInferFlagName,
+				Usage: "Only watch services with a docker-compose-watcher.path label, instead of watching the build context of the others",
+			},
+			&cli.DurationFlag{
+				Name:  debounceFlagName,
+				Value: business.DefaultDebounce,
+				Usage: "How long changes must settle before they are acted on, unless a service has a docker-compose-watcher.debounce label",
+			},
+			&cli.BoolFlag{
+				Name:  downOnExitFlagName,
+				Usage: "Remove the containers and networks of the project with 'docker compose down' on exit",
+			},
+			&cli.BoolFlag{
+				Name:    detachFlagName,
+				Aliases: []string{"d"},
+				Usage:   "Start the containers in the background and follow their logs, instead of restarting 'docker compose up' on every change",
 			},
 		},
 		Action: func(ctx *cli.Context) error {
-			c, err := business.NewComposeController(ctx.StringSlice(fileFlagName)...)
-			defer c.Close()
+			backend, err := parseBackend(ctx.String(backendFlagName))
+			if err != nil {
+				return err
+			}
+			debounce := ctx.Duration(debounceFlagName)
+			if debounce <= 0 {
+				return fmt.Errorf("invalid debounce '%v', must be positive", debounce)
+			}
+			files := ctx.StringSlice(fileFlagName)
+			if len(files) == 0 {
+				if files, err = discoverFiles(); err != nil {
+					return err
+				}
+			}
+			c, err := business.NewComposeController(business.ComposeControllerOptions{
+				Files:        files,
+				Backend:      backend,
+				Ignore:       ctx.StringSlice(ignoreFlagName),
+				Dockerignore: ctx.Bool(dockerignoreFlagName),
+				InferPaths:   !ctx.Bool(noInferFlagName),
+				Debounce:     debounce,
+				DownOnExit:   ctx.Bool(downOnExitFlagName),
+				Detach:       ctx.Bool(detachFlagName),
+				EnvFile:      ctx.String(envFileFlagName),
+				Profiles:     ctx.StringSlice(profileFlagName),
+			})
 			if err != nil {
-				panic(err)
+				return err
 			}
-			if err := c.Run(); err != nil {
-				panic(err)
+			sig := make(chan os.Signal, 2)
+			signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+			sctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-sig
+				cancel()
+				// a second interrupt while shutting down exits immediately, and
+				// kills the child processes, which do not receive it themselves
+				<-sig
+				c.Kill()
+				os.Exit(1)
+			}()
+			err = c.Run(sctx)
+			cancel()
+			if cerr := c.Close(); err == nil {
+				err = cerr
 			}
-			return nil
+			return err
 		},
 	}
-	app.Run(os.Args)
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
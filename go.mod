@@ -1,12 +1,18 @@
 module docker-compose-watcher
 
-go 1.13
+go 1.18
 
 require (
 	github.com/fsnotify/fsnotify v1.4.7
-	github.com/golang/mock v1.3.1 // indirect
 	github.com/pkg/errors v0.8.1
 	github.com/urfave/cli/v2 v2.1.1
-	golang.org/x/sys v0.0.0-20191210023423-ac6580df4449 // indirect
 	gopkg.in/yaml.v2 v2.2.7
 )
+
+require (
+	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
+	github.com/golang/mock v1.3.1 // indirect
+	github.com/russross/blackfriday/v2 v2.0.1 // indirect
+	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20191210023423-ac6580df4449 // indirect
+)
@@ -0,0 +1,163 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/provider/translator"
+	"docker-compose-watcher/pkg/dockercompose"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// upServices recreates the containers of the specified services, leaving the
+// rest of the project (and the attached 'up' or the followed logs) running.
+func (c *ComposeController) upServices(services ...string) error {
+	u := c.cmd.Up(dockercompose.UpOptions{Detach: true, NoDeps: true}, services...)
+	u.Stdout = os.Stdout
+	u.Stderr = os.Stderr
+	if err := u.Run(); err != nil {
+		return errors.Wrapf(err, "docker compose up of %v failed", services)
+	}
+	if c.detach {
+		return c.followLogs()
+	}
+	return nil
+}
+
+// restartServices restarts the containers of the specified services.
+func (c *ComposeController) restartServices(services ...string) error {
+	r := c.cmd.Restart(dockercompose.RestartOptions{}, services...)
+	r.Stdout = os.Stdout
+	r.Stderr = os.Stderr
+	if err := r.Run(); err != nil {
+		return errors.Wrapf(err, "docker compose restart of %v failed", services)
+	}
+	return nil
+}
+
+// dependentsToRestart returns the active services that depend on the rebuilt
+// services with the restart-dependents label, directly or transitively, in
+// topological order.
+func (c *ComposeController) dependentsToRestart(rebuilt []string) []string {
+	isRebuilt := make(map[string]bool, len(rebuilt))
+	var queue []string
+	for _, v := range rebuilt {
+		isRebuilt[v] = true
+		if c.services[v].RestartDependents {
+			queue = append(queue, c.services[v].Dependents...)
+		}
+	}
+	seen := make(map[string]bool)
+	var dependents []string
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		// rebuilt dependents were recreated already, but their dependents were not
+		if !isRebuilt[v] && c.services[v].Active(c.profiles) {
+			dependents = append(dependents, v)
+		}
+		queue = append(queue, c.services[v].Dependents...)
+	}
+	sort.Slice(dependents, func(i, j int) bool {
+		return c.order[dependents[i]] < c.order[dependents[j]]
+	})
+	return dependents
+}
+
+// restartDependents restarts the dependents of the rebuilt services one by one,
+// so that every service is restarted after the services it depends on.
+func (c *ComposeController) restartDependents(rebuilt []string) error {
+	for _, v := range c.dependentsToRestart(rebuilt) {
+		if err := c.restartServices(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execServices runs the exec command of each of the specified services in its container.
+func (c *ComposeController) execServices(services ...string) error {
+	for _, v := range services {
+		s := c.services[v]
+		e := c.cmd.Exec(dockercompose.ExecOptions{
+			NoTTY:   true,
+			User:    s.ExecUser,
+			Workdir: s.ExecWorkdir,
+			Env:     s.ExecEnv,
+		}, v, "sh", "-c", s.Exec)
+		e.Stdout = os.Stdout
+		e.Stderr = os.Stderr
+		if err := e.Run(); err != nil {
+			return errors.Wrapf(err, "docker compose exec of '%s' in %v failed", s.Exec, v)
+		}
+	}
+	return nil
+}
+
+func isSync(a translator.Action) bool {
+	return a == translator.ActionSync || a == translator.ActionSyncRestart
+}
+
+func hasAction(changes []change, actions ...translator.Action) bool {
+	for _, c := range changes {
+		for _, a := range actions {
+			if c.target.action == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// servicesChanged performs the actions of the services with changes. A service
+// is rebuilt if any of its changes requires it, and otherwise the changes are
+// synced before the service is restarted or its command is run.
+func (c *ComposeController) servicesChanged(changes map[string][]change) error {
+	services := make([]string, 0, len(changes))
+	for k := range changes {
+		services = append(services, k)
+	}
+	sort.Strings(services)
+	var rebuild, restart, exec []string
+	for _, v := range services {
+		ch := changes[v]
+		if hasAction(ch, translator.ActionBuild) {
+			rebuild = append(rebuild, v)
+			continue
+		}
+		if hasAction(ch, translator.ActionSync, translator.ActionSyncRestart) {
+			ok, err := c.syncService(v, ch)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				rebuild = append(rebuild, v)
+				continue
+			}
+		}
+		if hasAction(ch, translator.ActionRestart, translator.ActionSyncRestart) {
+			restart = append(restart, v)
+		}
+		if hasAction(ch, translator.ActionExec) {
+			exec = append(exec, v)
+		}
+	}
+	if len(rebuild) > 0 {
+		c.startBuild(rebuild)
+	}
+	if len(restart) > 0 {
+		if err := c.restartServices(restart...); err != nil {
+			return err
+		}
+	}
+	if len(exec) > 0 {
+		if err := c.execServices(exec...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/provider/translator"
+	"reflect"
+	"testing"
+)
+
+func TestComposeController_dependentsToRestart(t *testing.T) {
+	services := map[string]translator.WatchedService{
+		"db": {
+			Name:              "db",
+			Dependents:        []string{"api", "worker"},
+			RestartDependents: true,
+		},
+		"api": {
+			Name:       "api",
+			DependsOn:  []string{"db"},
+			Dependents: []string{"web"},
+		},
+		"worker": {
+			Name:      "worker",
+			DependsOn: []string{"db"},
+			Profiles:  []string{"jobs"},
+		},
+		"web": {
+			Name:      "web",
+			DependsOn: []string{"api"},
+		},
+	}
+	order := map[string]int{"db": 0, "api": 1, "worker": 2, "web": 3}
+	tests := []struct {
+		name     string
+		rebuilt  []string
+		profiles []string
+		want     []string
+	}{
+		{
+			name:    "transitive dependents",
+			rebuilt: []string{"db"},
+			want:    []string{"api", "web"},
+		},
+		{
+			name:     "active dependent",
+			rebuilt:  []string{"db"},
+			profiles: []string{"jobs"},
+			want:     []string{"api", "worker", "web"},
+		},
+		{
+			name:    "rebuilt dependent",
+			rebuilt: []string{"db", "api"},
+			want:    []string{"web"},
+		},
+		{
+			name:    "without label",
+			rebuilt: []string{"api"},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ComposeController{
+				services: services,
+				order:    order,
+				profiles: tt.profiles,
+			}
+			if got := c.dependentsToRestart(tt.rebuilt); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComposeController.dependentsToRestart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
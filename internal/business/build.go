@@ -0,0 +1,145 @@
+package business
+
+import (
+	"context"
+	"docker-compose-watcher/pkg/dockercompose"
+	"docker-compose-watcher/pkg/procgroup"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// build is a build of services that runs in the background.
+type build struct {
+	// services are the built services, or nil for all services
+	services []string
+	cancel   context.CancelFunc
+	done     chan struct{}
+	err      error
+}
+
+func (b *build) overlaps(services []string) bool {
+	if b.services == nil || services == nil {
+		return true
+	}
+	for _, v := range services {
+		for _, s := range b.services {
+			if v == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeServices returns the services of both lists, where nil is all services.
+func mergeServices(a, b []string) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	m := make(map[string]struct{}, len(a)+len(b))
+	for _, v := range a {
+		m[v] = struct{}{}
+	}
+	for _, v := range b {
+		m[v] = struct{}{}
+	}
+	services := make([]string, 0, len(m))
+	for k := range m {
+		services = append(services, k)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// cancelBuilds cancels the builds that overlap with the services and waits for
+// them to exit. It returns the services with the services of the cancelled builds.
+func (c *ComposeController) cancelBuilds(services []string) []string {
+	for i := 0; i < len(c.builds); {
+		b := c.builds[i]
+		if !b.overlaps(services) {
+			i++
+			continue
+		}
+		b.cancel()
+		<-b.done
+		c.builds = append(c.builds[:i], c.builds[i+1:]...)
+		services = mergeServices(services, b.services)
+		// the merged services may overlap with builds that were skipped
+		i = 0
+	}
+	return services
+}
+
+// startBuild builds the services in the background, or all services if nil.
+// Running builds of the same services are cancelled, and their services are
+// built again with the latest changes.
+func (c *ComposeController) startBuild(services []string) {
+	n := len(c.builds)
+	services = c.cancelBuilds(services)
+	if len(c.builds) < n {
+		log.Printf("restarting build of %v with the latest changes", services)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &build{
+		services: services,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	c.builds = append(c.builds, b)
+	cmd := c.cmd.Build(dockercompose.BuildOptions{}, services...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	go func() {
+		b.err = c.children.start(cmd, func() error {
+			return procgroup.Start(cmd)
+		})
+		if b.err == nil {
+			b.err = procgroup.Wait(ctx, cmd)
+			c.children.remove(cmd)
+		}
+		close(b.done)
+		select {
+		case c.built <- b:
+		case <-c.quit:
+		}
+	}()
+}
+
+// buildFinished starts the containers of a finished build. Builds that were
+// cancelled are ignored.
+func (c *ComposeController) buildFinished(b *build) error {
+	i := 0
+	for i < len(c.builds) && c.builds[i] != b {
+		i++
+	}
+	if i == len(c.builds) {
+		return nil
+	}
+	c.builds = append(c.builds[:i], c.builds[i+1:]...)
+	b.cancel()
+	if b.err != nil {
+		if b.services == nil {
+			return errors.Wrap(b.err, "docker compose build failed")
+		}
+		return errors.Wrapf(b.err, "docker compose build of %v failed", b.services)
+	}
+	if b.services == nil {
+		return c.startUp()
+	}
+	if err := c.upServices(b.services...); err != nil {
+		return err
+	}
+	return c.restartDependents(b.services)
+}
+
+// stopBuilds cancels all running builds and waits for them to exit.
+func (c *ComposeController) stopBuilds() {
+	for _, b := range c.builds {
+		b.cancel()
+		<-b.done
+	}
+	c.builds = nil
+}
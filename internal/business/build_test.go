@@ -0,0 +1,120 @@
+package business
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testBuild returns a running build of the services that exits when it is cancelled.
+func testBuild(services ...string) *build {
+	b := &build{services: services, done: make(chan struct{})}
+	b.cancel = func() {
+		close(b.done)
+	}
+	return b
+}
+
+func cancelled(b *build) bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestMergeServices(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want []string
+	}{
+		{"all and some", nil, []string{"a"}, nil},
+		{"some and all", []string{"a"}, nil, nil},
+		{"disjoint", []string{"b"}, []string{"c", "a"}, []string{"a", "b", "c"}},
+		{"overlapping", []string{"a", "b"}, []string{"b"}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeServices(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeServices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComposeController_cancelBuilds(t *testing.T) {
+	tests := []struct {
+		name     string
+		builds   [][]string
+		services []string
+		want     []string
+		// wantCancelled are the indexes of the cancelled builds
+		wantCancelled []int
+	}{
+		{
+			name:          "no overlap",
+			builds:        [][]string{{"a"}, {"b"}},
+			services:      []string{"c"},
+			want:          []string{"c"},
+			wantCancelled: nil,
+		},
+		{
+			name:          "overlap",
+			builds:        [][]string{{"a"}, {"b", "c"}},
+			services:      []string{"c", "d"},
+			want:          []string{"b", "c", "d"},
+			wantCancelled: []int{1},
+		},
+		{
+			name:          "build of all services",
+			builds:        [][]string{nil, {"b"}},
+			services:      []string{"a"},
+			want:          nil,
+			wantCancelled: []int{0, 1},
+		},
+		{
+			name:          "all services",
+			builds:        [][]string{{"a"}, {"b"}},
+			services:      nil,
+			want:          nil,
+			wantCancelled: []int{0, 1},
+		},
+		{
+			name: "overlap after merge",
+			// the merged services of the second build overlap with the first
+			builds:        [][]string{{"b"}, {"a", "b"}, {"c"}},
+			services:      []string{"a"},
+			want:          []string{"a", "b"},
+			wantCancelled: []int{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ComposeController{}
+			for _, v := range tt.builds {
+				c.builds = append(c.builds, testBuild(v...))
+			}
+			builds := append([]*build(nil), c.builds...)
+			if got := c.cancelBuilds(tt.services); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComposeController.cancelBuilds() = %v, want %v", got, tt.want)
+			}
+			var gotCancelled []int
+			running := []*build{}
+			for k, v := range builds {
+				if cancelled(v) {
+					gotCancelled = append(gotCancelled, k)
+				} else {
+					running = append(running, v)
+				}
+			}
+			if !reflect.DeepEqual(gotCancelled, tt.wantCancelled) {
+				t.Errorf("ComposeController.cancelBuilds() cancelled %v, want %v", gotCancelled, tt.wantCancelled)
+			}
+			if got := append([]*build{}, c.builds...); !reflect.DeepEqual(got, running) {
+				t.Errorf("ComposeController.builds = %v, want %v", got, running)
+			}
+		})
+	}
+}
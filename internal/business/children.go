@@ -0,0 +1,82 @@
+package business
+
+import (
+	"docker-compose-watcher/pkg/procgroup"
+	"docker-compose-watcher/pkg/supervisor"
+	"os/exec"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// children are the running child processes of the controller. They run in
+// their own process groups, so they do not receive the interrupts of the
+// terminal and must be killed explicitly when the controller exits immediately.
+type children struct {
+	mtx    sync.Mutex
+	cmds   map[*exec.Cmd]struct{}
+	killed bool
+}
+
+func newChildren() *children {
+	return &children{cmds: make(map[*exec.Cmd]struct{})}
+}
+
+// start starts the command with the start function and adds it to the children,
+// unless the children were killed.
+func (s *children) start(cmd *exec.Cmd, start func() error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.killed {
+		return errors.New("child processes were killed")
+	}
+	if err := start(); err != nil {
+		return err
+	}
+	s.cmds[cmd] = struct{}{}
+	return nil
+}
+
+// remove removes a command that has exited from the children.
+func (s *children) remove(cmd *exec.Cmd) {
+	s.mtx.Lock()
+	delete(s.cmds, cmd)
+	s.mtx.Unlock()
+}
+
+// kill kills the process groups of the children, and prevents new children
+// from being started. It is safe to call while the controller is running.
+func (s *children) kill() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.killed = true
+	for cmd := range s.cmds {
+		procgroup.Kill(cmd)
+	}
+	s.cmds = make(map[*exec.Cmd]struct{})
+}
+
+// startSupervised starts a supervised child process, which is removed from the
+// children when it exits.
+func (c *ComposeController) startSupervised(cmd *exec.Cmd) (*supervisor.Process, error) {
+	var p *supervisor.Process
+	err := c.children.start(cmd, func() (err error) {
+		p, err = supervisor.Start(cmd)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-p.Done()
+		c.children.remove(cmd)
+	}()
+	return p, nil
+}
+
+// Kill kills the child processes of the controller, i.e. 'up', the builds and
+// the followed logs, for when it must exit immediately. It is safe to call
+// while the controller is running or closing.
+func (c *ComposeController) Kill() {
+	c.children.kill()
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package business
+
+import (
+	"docker-compose-watcher/pkg/procgroup"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestChildren_kill(t *testing.T) {
+	s := newChildren()
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30; wait")
+	if err := s.start(cmd, func() error {
+		return procgroup.Start(cmd)
+	}); err != nil {
+		t.Fatalf("children.start() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	s.kill()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("exec.Cmd.Wait() error = nil, want killed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("children.kill() did not kill %v", cmd)
+	}
+	cmd = exec.Command("sh", "-c", "exit 0")
+	started := false
+	err := s.start(cmd, func() error {
+		started = true
+		return procgroup.Start(cmd)
+	})
+	if err == nil || started {
+		t.Errorf("children.start() after kill error = %v, started %v", err, started)
+	}
+}
@@ -1,92 +1,276 @@
 package business
 
 import (
+	"context"
 	padapter "docker-compose-watcher/internal/provider/adapter"
 	"docker-compose-watcher/internal/provider/translator"
 	"docker-compose-watcher/internal/rlistener"
 	rfsnotify "docker-compose-watcher/internal/rlistener/watcher/fsnotify"
 	"docker-compose-watcher/pkg/chanthrottler"
 	"docker-compose-watcher/pkg/dockercompose"
+	"docker-compose-watcher/pkg/dockercompose/service"
 	"docker-compose-watcher/pkg/provider"
 	pfsnotify "docker-compose-watcher/pkg/provider/watcher/fsnotify"
+	"docker-compose-watcher/pkg/supervisor"
 	"github.com/pkg/errors"
+	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
 )
 
-const throttleDuration = 500 * time.Millisecond
+// DefaultDebounce is how long changes must settle before they are acted on.
+const DefaultDebounce = 500 * time.Millisecond
+
+// upStopTimeout is how long the foreground 'up' may take to stop the
+// containers after it was interrupted, before it is killed.
+const upStopTimeout = 30 * time.Second
 
 // ComposeController controls compose.
 type ComposeController struct {
-	p   *provider.Provider
-	l   *rlistener.Listener
-	cmd *dockercompose.Commander
-	exe *exec.Cmd
-	rch <-chan provider.ReaderValueWithError
+	p            *provider.Provider
+	reader       *service.Reader
+	d            *debouncer
+	pd           *chanthrottler.Debouncer[provider.ReaderValueWithError]
+	cmd          *dockercompose.Commander
+	up           *supervisor.Process
+	services     map[string]translator.WatchedService
+	order        map[string]int
+	ignore       []string
+	dockerignore bool
+	inferPaths   bool
+	debounce     time.Duration
+	builds       []*build
+	built        chan *build
+	quit         chan struct{}
+	downOnExit   bool
+	detach       bool
+	logs         *supervisor.Process
+	logsFollowed bool
+	profiles     []string
+	children     *children
 }
 
+// rebuildAndRestart stops the project and rebuilds all services in the
+// background. The project is started again when the build has finished.
 func (c *ComposeController) rebuildAndRestart() error {
-	if c.exe != nil {
-		if err := c.exe.Process.Signal(os.Interrupt); err != nil {
-			return errors.Wrap(err, "failed to send interrupt signal to process")
-		}
+	if err := c.stopUp(); err != nil {
+		return err
+	}
+	c.startBuild(nil)
+	return nil
+}
+
+// startUp starts the project in the foreground, once the previous 'up' has
+// stopped, or in the background in detached mode.
+func (c *ComposeController) startUp() error {
+	if c.detach {
+		return c.upDetached()
 	}
-	c.exe = c.cmd.Build(dockercompose.BuildOptions{})
-	c.exe.Stdout = os.Stdout
-	c.exe.Stderr = os.Stderr
-	if err := c.exe.Run(); err != nil {
-		return errors.Wrap(err, "docker compose build failed")
+	if err := c.stopUp(); err != nil {
+		return err
 	}
-	c.exe = c.cmd.Up(dockercompose.UpOptions{})
-	c.exe.Stdout = os.Stdout
-	c.exe.Stderr = os.Stderr
-	return c.exe.Start()
+	u := c.cmd.Up(dockercompose.UpOptions{})
+	u.Stdout = os.Stdout
+	u.Stderr = os.Stderr
+	p, err := c.startSupervised(u)
+	if err != nil {
+		return errors.Wrap(err, "failed to start docker compose up")
+	}
+	c.up = p
+	return nil
 }
 
-func (c *ComposeController) servicesUpdated(services map[string]translator.WatchedService) error {
-	var err error
-	if err := c.l.Close(); err != nil {
-		return errors.Wrap(err, "failed to close previous rlistener")
+// stopUp interrupts the foreground 'up' and waits for it to stop the
+// containers. It is killed if it takes longer than upStopTimeout.
+func (c *ComposeController) stopUp() error {
+	if c.up == nil {
+		return nil
+	}
+	p := c.up
+	c.up = nil
+	if err := p.Stop(upStopTimeout); err != nil {
+		return err
+	}
+	if p.Killed() {
+		log.Printf("docker compose up did not stop within %v and was killed", upStopTimeout)
 	}
-	c.l, err = rlistener.New(rfsnotify.New)
+	return nil
+}
+
+// upExited returns a channel that is closed when the foreground 'up' exits.
+func (c *ComposeController) upExited() <-chan struct{} {
+	if c.up == nil {
+		return nil
+	}
+	return c.up.Done()
+}
+
+// down stops and removes the containers of the project.
+func (c *ComposeController) down() error {
+	d := c.cmd.Down(dockercompose.DownOptions{})
+	d.Stdout = os.Stdout
+	d.Stderr = os.Stderr
+	if err := d.Run(); err != nil {
+		return errors.Wrap(err, "docker compose down failed")
+	}
+	return nil
+}
+
+// serviceFilters returns the filters of the paths to ignore in the source dir of the service.
+func (c *ComposeController) serviceFilters(dir string, s translator.WatchedService, ignore []string) ([]rlistener.Filter, error) {
+	f, err := newIgnoreFilter(dir, c.ignore, ignore)
 	if err != nil {
-		return errors.Wrap(err, "failed to create rlistener")
+		return nil, errors.Wrapf(err, "invalid ignore patterns of service %v", s.Name)
+	}
+	filters := []rlistener.Filter{f}
+	if !c.dockerignore || s.BuildContext == "" {
+		return filters, nil
+	}
+	context, err := filepath.Abs(filepath.Join(s.Directory, s.BuildContext))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get absolute path of %v", s.BuildContext)
+	}
+	df, err := dockerfilePath(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get absolute path of the Dockerfile of %v", s.Name)
+	}
+	d, err := newDockerignoreFilter(context, df)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load .dockerignore of service %v", s.Name)
+	}
+	if d != nil {
+		filters = append(filters, d)
+	}
+	return filters, nil
+}
+
+// listenTargets adds the targets of the services to the listener, and returns
+// them with the debounce durations of the services.
+func (c *ComposeController) listenTargets(l *rlistener.Listener, services map[string]translator.WatchedService) ([]watchTarget, map[string]time.Duration, error) {
+	var all []watchTarget
+	durations := make(map[string]time.Duration, len(services))
+	for _, v := range services {
+		durations[v.Name] = v.Debounce
+		targets, err := c.watchTargets(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range targets {
+			if isSync(t.action) && c.cmd.Backend() == dockercompose.BackendV1 {
+				return nil, nil, errors.Errorf("action '%s' of service %s requires the docker compose V2 plugin", t.action, v.Name)
+			}
+			if t.file {
+				if err := l.AddFile(t.path); err != nil {
+					return nil, nil, errors.Wrapf(err, "failed to listen to source file %v", t.path)
+				}
+				continue
+			}
+			if err := l.AddDir(t.path, t.filters...); err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to listen to source dir %v", t.path)
+			}
+		}
+		all = append(all, targets...)
+	}
+	return all, durations, nil
+}
+
+// watchReadFiles re-reads the services when a file that they were read from
+// changes, which includes the files of the services that they extend.
+func (c *ComposeController) watchReadFiles() error {
+	for _, v := range c.reader.Files() {
+		if err := c.p.Watch(v); err != nil {
+			return errors.Wrapf(err, "failed to watch %v", v)
+		}
+	}
+	return nil
+}
+
+// watchDockerignores re-reads the services when the .dockerignore file of a
+// build context changes, so that the filters of the services are updated.
+func (c *ComposeController) watchDockerignores(services map[string]translator.WatchedService) error {
+	if !c.dockerignore {
+		return nil
 	}
 	for _, v := range services {
-		if v.Path == "" {
+		if v.BuildContext == "" || isRemoteContext(v.BuildContext) {
 			continue
 		}
-		p := filepath.Join(v.Directory, v.Path)
-		if err := c.l.AddDir(p); err != nil {
-			return errors.Wrapf(err, "failed to listen to source dir %v", p)
+		f := filepath.Join(v.Directory, v.BuildContext, dockerignoreFile)
+		if _, err := os.Stat(f); err != nil {
+			continue
+		}
+		if err := c.p.Watch(f); err != nil {
+			return errors.Wrapf(err, "failed to watch %v", f)
 		}
 	}
+	return nil
+}
+
+func (c *ComposeController) servicesUpdated(services map[string]translator.WatchedService) error {
+	if err := c.d.Close(); err != nil {
+		return errors.Wrap(err, "failed to close previous rlistener")
+	}
+	l, err := rlistener.New(rfsnotify.New)
+	if err != nil {
+		return errors.Wrap(err, "failed to create rlistener")
+	}
+	c.services = services
+	deps := make(map[string][]string, len(services))
+	for k, v := range services {
+		deps[k] = v.DependsOn
+	}
+	order, err := service.TopologicalOrder(deps)
+	if err != nil {
+		l.Close()
+		return err
+	}
+	c.order = make(map[string]int, len(order))
+	for k, v := range order {
+		c.order[v] = k
+	}
+	targets, durations, err := c.listenTargets(l, services)
+	c.d = newDebouncer(l, targets, durations, c.debounce)
+	if err != nil {
+		return err
+	}
+	if err := c.watchReadFiles(); err != nil {
+		return err
+	}
+	if err := c.watchDockerignores(services); err != nil {
+		return err
+	}
 	return c.rebuildAndRestart()
 }
 
-// Run runs the compose controller execution loop.
-func (c *ComposeController) Run() error {
+// Run runs the compose controller execution loop until ctx is done.
+func (c *ComposeController) Run(ctx context.Context) error {
 	c.p.Sync()
 	for {
 		select {
-		case vi, ok := <-chanthrottler.Throttle(throttleDuration, c.l.Channel()):
-			if !ok {
-				break
+		case <-ctx.Done():
+			return nil
+		case b := <-c.d.Channel():
+			b = c.d.mergeReady(b)
+			if b.err != nil {
+				return b.err
 			}
-			v := vi.(rlistener.ListenerMsg)
-			if v.Error != nil {
-				return v.Error
+			if err := c.servicesChanged(b.changes); err != nil {
+				return err
 			}
-			if err := c.rebuildAndRestart(); err != nil {
+		case <-c.upExited():
+			log.Printf("docker compose up exited with code %d", c.up.ExitCode())
+			c.up = nil
+		case <-c.logsExited():
+			c.logs = nil
+		case b := <-c.built:
+			if err := c.buildFinished(b); err != nil {
 				return err
 			}
-		case vi, ok := <-chanthrottler.Throttle(throttleDuration, c.rch):
+		case v, ok := <-c.pd.Channel():
 			if !ok {
-				break
+				return nil
 			}
-			v := vi.(provider.ReaderValueWithError)
 			if v.Error != nil {
 				return v.Error
 			}
@@ -97,14 +281,84 @@ func (c *ComposeController) Run() error {
 	}
 }
 
-// Close cleans up the controller.
+// Close stops the builds and the watchers, and then stops the project. The
+// containers are removed too if the controller was created with DownOnExit.
 func (c *ComposeController) Close() error {
-	return c.p.Close()
+	close(c.quit)
+	c.stopBuilds()
+	// nobody reads the debounced services anymore, so their debouncer must
+	// drain the provider's values before the provider can be closed
+	c.pd.Stop()
+	err := c.p.Close()
+	if derr := c.d.Close(); err == nil {
+		err = derr
+	}
+	if uerr := c.stopUp(); err == nil {
+		err = uerr
+	}
+	if lerr := c.stopLogs(); err == nil {
+		err = lerr
+	}
+	switch {
+	case c.downOnExit:
+		if derr := c.down(); err == nil {
+			err = derr
+		}
+	case c.detach:
+		if serr := c.stopContainers(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// ComposeControllerOptions specifies how the compose controller should run.
+type ComposeControllerOptions struct {
+	// Files are the Docker Compose files of the project.
+	Files []string
+	// Backend is the Docker Compose implementation to run commands with.
+	Backend dockercompose.Backend
+	// Ignore are gitignore-style patterns of paths to ignore in every source directory.
+	Ignore []string
+	// Dockerignore ignores the paths excluded by the .dockerignore of a service's build context.
+	Dockerignore bool
+	// InferPaths watches the build context of services without a path label.
+	InferPaths bool
+	// Debounce is how long changes must settle before they are acted on, for
+	// services without a debounce label. Zero means DefaultDebounce.
+	Debounce time.Duration
+	// DownOnExit removes the containers of the project when the controller is closed.
+	DownOnExit bool
+	// EnvFile is the file of the variables that are interpolated in the Docker
+	// Compose files, instead of the .env file of the project.
+	EnvFile string
+	// Profiles are the profiles of the services to run and watch.
+	Profiles []string
+	// Detach starts the containers in the background and follows their logs,
+	// instead of running 'up' in the foreground.
+	Detach bool
+}
+
+// watchEnvFile re-reads the services when the env file changes. The .env file
+// of the project is optional, so it is only watched if it exists.
+func watchEnvFile(x *provider.Provider, opt ComposeControllerOptions) error {
+	f := opt.EnvFile
+	if f == "" {
+		f = service.ProjectEnvFile(opt.Files)
+		if _, err := os.Stat(f); f == "" || err != nil {
+			return nil
+		}
+	}
+	if err := x.Watch(f); err != nil {
+		return errors.Wrapf(err, "failed to watch env file %v", f)
+	}
+	return nil
 }
 
 // NewComposeController creates a new compose controller.
-func NewComposeController(composePaths ...string) (*ComposeController, error) {
-	x, err := provider.New(padapter.NewServiceReader, pfsnotify.New)
+func NewComposeController(opt ComposeControllerOptions) (*ComposeController, error) {
+	reader := service.NewReaderWithEnvFile(opt.EnvFile)
+	x, err := provider.New(padapter.NewServiceReaderFrom(reader), pfsnotify.New)
 	if err != nil {
 		return nil, err
 	}
@@ -112,21 +366,42 @@ func NewComposeController(composePaths ...string) (*ComposeController, error) {
 	if err != nil {
 		return nil, err
 	}
-	for _, v := range composePaths {
+	for _, v := range opt.Files {
 		err := x.Add(v)
 		if err != nil {
 			x.Close()
 			return nil, err
 		}
 	}
-	c := dockercompose.NewCommander(dockercompose.CommanderOptions{
-		Files: composePaths,
+	if err := watchEnvFile(x, opt); err != nil {
+		x.Close()
+		return nil, err
+	}
+	c := dockercompose.NewCommanderWithBackend(opt.Backend, dockercompose.CommanderOptions{
+		Files:    opt.Files,
+		EnvFile:  opt.EnvFile,
+		Profiles: opt.Profiles,
 	})
+	debounce := opt.Debounce
+	if debounce == 0 {
+		debounce = DefaultDebounce
+	}
 	r := translator.NewServiceTranslatorChannel(x.Channel())
 	return &ComposeController{
-		p:   x,
-		cmd: c,
-		rch: r,
-		l:   l,
+		p:            x,
+		reader:       reader,
+		cmd:          c,
+		pd:           chanthrottler.New(r, debounce, chanthrottler.Options{}),
+		d:            newDebouncer(l, nil, nil, debounce),
+		ignore:       opt.Ignore,
+		dockerignore: opt.Dockerignore,
+		inferPaths:   opt.InferPaths,
+		debounce:     debounce,
+		built:        make(chan *build),
+		quit:         make(chan struct{}),
+		downOnExit:   opt.DownOnExit,
+		detach:       opt.Detach,
+		profiles:     opt.Profiles,
+		children:     newChildren(),
 	}, nil
 }
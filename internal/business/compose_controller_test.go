@@ -0,0 +1,99 @@
+package business
+
+import (
+	padapter "docker-compose-watcher/internal/provider/adapter"
+	"docker-compose-watcher/pkg/dockercompose/service"
+	"docker-compose-watcher/pkg/provider"
+	pfsnotify "docker-compose-watcher/pkg/provider/watcher/fsnotify"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComposeController_watchReadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compose_controller_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "base"), 0700); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	file := filepath.Join(dir, "compose.yaml")
+	common := filepath.Join(dir, "base", "common.yaml")
+	writeFile := func(name, content string) {
+		if err := ioutil.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %v: %v", name, err)
+		}
+	}
+	writeFile(file, `
+services:
+  app:
+    extends:
+      file: base/common.yaml
+      service: base
+`)
+	writeFile(common, `
+services:
+  base:
+    labels:
+      docker-compose-watcher.path: ./v1
+`)
+	reader := service.NewReader()
+	p, err := provider.New(padapter.NewServiceReaderFrom(reader), pfsnotify.New)
+	if err != nil {
+		t.Fatalf("provider.New() error = %v", err)
+	}
+	if err := p.Add(file); err != nil {
+		t.Fatalf("Provider.Add() error = %v", err)
+	}
+	// path waits for the services to be read with the path label of app. The
+	// values of a partially written file are skipped.
+	path := func(want string) bool {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case v := <-p.Channel():
+				if v.Error != nil {
+					continue
+				}
+				services := v.Value.(map[string]service.LabelledService)
+				if services["app"].Labels["docker-compose-watcher.path"] == want {
+					return true
+				}
+			case <-timeout:
+				return false
+			}
+		}
+	}
+	// the services are read once they are added
+	if !path("base/v1") {
+		t.Fatalf("Provider.Channel() did not send the services")
+	}
+	c := &ComposeController{p: p, reader: reader}
+	if err := c.watchReadFiles(); err != nil {
+		t.Fatalf("ComposeController.watchReadFiles() error = %v", err)
+	}
+	writeFile(common, `
+services:
+  base:
+    labels:
+      docker-compose-watcher.path: ./v2
+`)
+	if !path("base/v2") {
+		t.Errorf("Provider.Channel() did not send the services after the extended file changed")
+	}
+	// the remaining values of the change are drained, so that the provider can be closed
+	done := make(chan struct{})
+	go func() {
+		for range p.Channel() {
+		}
+		close(done)
+	}()
+	if err := p.Close(); err != nil {
+		t.Errorf("Provider.Close() error = %v", err)
+	}
+	<-done
+}
@@ -0,0 +1,161 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/rlistener"
+	"docker-compose-watcher/pkg/chanthrottler"
+	"sync"
+	"time"
+)
+
+// maxWaitFactor bounds how long the changes of a service are held back while
+// its files keep changing, as a multiple of its debounce duration.
+const maxWaitFactor = 10
+
+// changeKey tells apart the changes of a batch by target, path and operation.
+type changeKey struct {
+	target *watchTarget
+	path   string
+	op     rlistener.Operation
+}
+
+// batch is a set of changes of the services, or the error of the listener.
+type batch struct {
+	changes map[string][]change
+	err     error
+}
+
+// merge adds the changes of b to the batch.
+func (x batch) merge(b batch) batch {
+	if x.err != nil {
+		return x
+	}
+	if b.err != nil {
+		return b
+	}
+	for k, v := range b.changes {
+		x.changes[k] = append(x.changes[k], v...)
+	}
+	return x
+}
+
+// serviceDebouncer debounces the changes of a single service with the
+// service's own duration, so that a noisy service does not delay the others.
+type serviceDebouncer struct {
+	ch chan change
+	b  *chanthrottler.Batcher[change]
+}
+
+// debouncer routes the messages of a listener to the debouncers of the
+// services watching their paths, and merges the batches of the services.
+type debouncer struct {
+	l        *rlistener.Listener
+	targets  []watchTarget
+	services map[string]serviceDebouncer
+	out      chan batch
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	closed   sync.Once
+}
+
+func (d *debouncer) send(b batch) bool {
+	select {
+	case d.out <- b:
+		return true
+	case <-d.quit:
+		return false
+	}
+}
+
+func (d *debouncer) route() {
+	defer func() {
+		for _, v := range d.services {
+			close(v.ch)
+		}
+	}()
+	// the listener is drained until it is closed, even after the debouncer was
+	// closed, so that it does not block on its sends
+	for m := range d.l.Channel() {
+		if m.Error != nil {
+			d.send(batch{err: m.Error})
+			continue
+		}
+		for k, v := range changedServices(d.targets, m) {
+			for _, c := range v {
+				d.services[k].ch <- c
+			}
+		}
+	}
+}
+
+func (d *debouncer) forward(service string, b *chanthrottler.Batcher[change]) {
+	defer d.wg.Done()
+	for v := range b.Channel() {
+		if !d.send(batch{changes: map[string][]change{service: v}}) {
+			return
+		}
+	}
+}
+
+// Channel returns the channel of the batches of changes.
+func (d *debouncer) Channel() <-chan batch {
+	return d.out
+}
+
+// mergeReady merges the batches of other services that are ready as well into
+// b, so that they are handled at once.
+func (d *debouncer) mergeReady(b batch) batch {
+	for {
+		select {
+		case v := <-d.out:
+			b = b.merge(v)
+		default:
+			return b
+		}
+	}
+}
+
+// Close closes the listener and stops the debouncers.
+func (d *debouncer) Close() error {
+	var err error
+	d.closed.Do(func() {
+		err = d.l.Close()
+		close(d.quit)
+		for _, v := range d.services {
+			v.b.Stop()
+		}
+		d.wg.Wait()
+	})
+	return err
+}
+
+// newDebouncer debounces the changes of the targets that are reported by the
+// listener. Services without a debounce duration use the default duration.
+func newDebouncer(l *rlistener.Listener, targets []watchTarget, durations map[string]time.Duration, def time.Duration) *debouncer {
+	d := &debouncer{
+		l:        l,
+		targets:  targets,
+		services: make(map[string]serviceDebouncer),
+		out:      make(chan batch),
+		quit:     make(chan struct{}),
+	}
+	for _, t := range targets {
+		if _, ok := d.services[t.service]; ok {
+			continue
+		}
+		wait := durations[t.service]
+		if wait == 0 {
+			wait = def
+		}
+		ch := make(chan change)
+		b := chanthrottler.NewBatcher(ch, wait, chanthrottler.Options{
+			MaxWait: maxWaitFactor * wait,
+		}, func(c change) changeKey {
+			return changeKey{c.target, c.msg.Path, c.msg.Operation}
+		})
+		d.services[t.service] = serviceDebouncer{ch, b}
+		d.wg.Add(1)
+		go d.forward(t.service, b)
+	}
+	go d.route()
+	return d
+}
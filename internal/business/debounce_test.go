@@ -0,0 +1,187 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/rlistener"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fakeWatcher is a watcher whose messages are sent by the test.
+type fakeWatcher struct {
+	ch     chan rlistener.WatcherMsg
+	closed sync.Once
+}
+
+func (w *fakeWatcher) AddDir(path string) error {
+	return nil
+}
+
+func (w *fakeWatcher) RemDir(path string) error {
+	return nil
+}
+
+func (w *fakeWatcher) Channel() <-chan rlistener.WatcherMsg {
+	return w.ch
+}
+
+func (w *fakeWatcher) Close() error {
+	w.closed.Do(func() {
+		close(w.ch)
+	})
+	return nil
+}
+
+// newTestDebouncer debounces the changes of services that watch their own
+// directory of a temporary directory, with the durations of the services.
+func newTestDebouncer(t *testing.T, durations map[string]time.Duration, buffer int) (*debouncer, *fakeWatcher, string) {
+	dir, err := ioutil.TempDir("", "debounce_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	w := &fakeWatcher{ch: make(chan rlistener.WatcherMsg, buffer)}
+	l, err := rlistener.New(func() (rlistener.Watcher, error) {
+		return w, nil
+	})
+	if err != nil {
+		t.Fatalf("rlistener.New() error = %v", err)
+	}
+	var targets []watchTarget
+	for k := range durations {
+		p := filepath.Join(dir, k)
+		if err := os.Mkdir(p, 0700); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := l.AddDir(p); err != nil {
+			t.Fatalf("Listener.AddDir() error = %v", err)
+		}
+		targets = append(targets, watchTarget{service: k, path: p})
+	}
+	return newDebouncer(l, targets, durations, time.Second), w, dir
+}
+
+func batchServices(b batch) []string {
+	var services []string
+	for k := range b.changes {
+		services = append(services, k)
+	}
+	sort.Strings(services)
+	return services
+}
+
+func receiveBatch(t *testing.T, d *debouncer, timeout time.Duration) (batch, bool) {
+	select {
+	case b := <-d.Channel():
+		return b, true
+	case <-time.After(timeout):
+		return batch{}, false
+	}
+}
+
+func TestDebouncer_perServiceTimers(t *testing.T) {
+	d, w, dir := newTestDebouncer(t, map[string]time.Duration{
+		"noisy": 200 * time.Millisecond,
+		"quiet": 50 * time.Millisecond,
+	}, 0)
+	defer d.Close()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.ch <- rlistener.WatcherMsg{Path: filepath.Join(dir, "quiet", "main.go"), Op: rlistener.Write}
+		// the noisy service keeps changing for longer than the quiet service's duration
+		for {
+			select {
+			case <-stop:
+				return
+			case w.ch <- rlistener.WatcherMsg{Path: filepath.Join(dir, "noisy", "main.go"), Op: rlistener.Write}:
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	b, ok := receiveBatch(t, d, time.Second)
+	close(stop)
+	<-done
+	if !ok {
+		t.Fatalf("debouncer.Channel() did not send the batch of the quiet service")
+	}
+	if got := batchServices(b); len(got) != 1 || got[0] != "quiet" {
+		t.Errorf("debouncer.Channel() services = %v, want [quiet]", got)
+	}
+	b, ok = receiveBatch(t, d, 5*time.Second)
+	if !ok {
+		t.Fatalf("debouncer.Channel() did not send the batch of the noisy service")
+	}
+	if got := batchServices(b); len(got) != 1 || got[0] != "noisy" {
+		t.Errorf("debouncer.Channel() services = %v, want [noisy]", got)
+	}
+	// the repeated changes of the same path are sent once
+	if n := len(b.changes["noisy"]); n != 1 {
+		t.Errorf("debouncer.Channel() sent %d changes of noisy, want 1", n)
+	}
+}
+
+func TestDebouncer_mergeReady(t *testing.T) {
+	d, w, dir := newTestDebouncer(t, map[string]time.Duration{
+		"a": 20 * time.Millisecond,
+		"b": 20 * time.Millisecond,
+	}, 0)
+	defer d.Close()
+	w.ch <- rlistener.WatcherMsg{Path: filepath.Join(dir, "a", "main.go"), Op: rlistener.Write}
+	w.ch <- rlistener.WatcherMsg{Path: filepath.Join(dir, "b", "main.go"), Op: rlistener.Write}
+	// both batches are ready while the first one is not received yet
+	time.Sleep(200 * time.Millisecond)
+	b, ok := receiveBatch(t, d, time.Second)
+	if !ok {
+		t.Fatalf("debouncer.Channel() did not send a batch")
+	}
+	b = d.mergeReady(b)
+	if got := batchServices(b); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("debouncer.mergeReady() services = %v, want [a b]", got)
+	}
+}
+
+func TestDebouncer_error(t *testing.T) {
+	d, w, _ := newTestDebouncer(t, map[string]time.Duration{
+		"a": 20 * time.Millisecond,
+	}, 0)
+	defer d.Close()
+	wantErr := errors.New("watcher failed")
+	w.ch <- rlistener.WatcherMsg{Err: wantErr}
+	b, ok := receiveBatch(t, d, time.Second)
+	if !ok {
+		t.Fatalf("debouncer.Channel() did not send the error")
+	}
+	if b.err != wantErr {
+		t.Errorf("debouncer.Channel() error = %v, want %v", b.err, wantErr)
+	}
+}
+
+func TestDebouncer_drainsAfterClose(t *testing.T) {
+	d, w, dir := newTestDebouncer(t, map[string]time.Duration{
+		"a": 20 * time.Millisecond,
+	}, 3)
+	// the error is not received before the debouncer is closed
+	w.ch <- rlistener.WatcherMsg{Err: errors.New("watcher failed")}
+	w.ch <- rlistener.WatcherMsg{Path: filepath.Join(dir, "a", "main.go"), Op: rlistener.Write}
+	w.ch <- rlistener.WatcherMsg{Path: filepath.Join(dir, "a", "other.go"), Op: rlistener.Write}
+	if err := d.Close(); err != nil {
+		t.Fatalf("debouncer.Close() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(w.ch) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the watcher was not drained after debouncer.Close(), %d messages left", len(w.ch))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,75 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/rlistener"
+	"docker-compose-watcher/pkg/ignore"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const dockerignoreFile = ".dockerignore"
+
+// ignoreFilter ignores the paths below dir that are matched by the patterns,
+// except for the kept paths and the directories containing them.
+type ignoreFilter struct {
+	dir  string
+	m    *ignore.Matcher
+	keep []string
+}
+
+func (f *ignoreFilter) Ignored(path string, isDir bool) bool {
+	for _, v := range f.keep {
+		if path == v || isDir && rlistener.Contains(path, v) {
+			return false
+		}
+	}
+	r, err := filepath.Rel(f.dir, path)
+	if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return f.m.Match(filepath.ToSlash(r), isDir)
+}
+
+func newIgnoreFilter(dir string, patterns ...[]string) (*ignoreFilter, error) {
+	var all []string
+	for _, v := range patterns {
+		all = append(all, v...)
+	}
+	m, err := ignore.New(all)
+	if err != nil {
+		return nil, err
+	}
+	return &ignoreFilter{dir: dir, m: m}, nil
+}
+
+// newDockerignoreFilter creates a filter from the .dockerignore file of the build
+// context. It returns nil if the context does not have a .dockerignore file.
+// Like Docker, which always sends them to the daemon, the filter never ignores
+// the Dockerfile and the .dockerignore file.
+func newDockerignoreFilter(context, dockerfile string) (*ignoreFilter, error) {
+	p := filepath.Join(context, dockerignoreFile)
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %v", p)
+	}
+	defer f.Close()
+	patterns, err := ignore.ReadPatterns(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", p)
+	}
+	m, err := ignore.NewDockerignore(patterns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid patterns in %v", p)
+	}
+	keep := []string{p}
+	if dockerfile != "" {
+		keep = append(keep, dockerfile)
+	}
+	return &ignoreFilter{dir: context, m: m, keep: keep}, nil
+}
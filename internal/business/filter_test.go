@@ -0,0 +1,94 @@
+package business
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDockerignoreFilter(t *testing.T) {
+	context, err := ioutil.TempDir("", "filter_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(context)
+	err = ioutil.WriteFile(filepath.Join(context, dockerignoreFile), []byte("*\n!src\nDockerfile\n.dockerignore\n"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write .dockerignore: %v", err)
+	}
+	tests := []struct {
+		name       string
+		dockerfile string
+		path       string
+		isDir      bool
+		want       bool
+	}{
+		{
+			name:       "whitelisted",
+			dockerfile: "Dockerfile",
+			path:       "src/main.go",
+			want:       false,
+		},
+		{
+			name:       "excluded",
+			dockerfile: "Dockerfile",
+			path:       "README.md",
+			want:       true,
+		},
+		{
+			name:       "Dockerfile",
+			dockerfile: "Dockerfile",
+			path:       "Dockerfile",
+			want:       false,
+		},
+		{
+			name:       ".dockerignore",
+			dockerfile: "Dockerfile",
+			path:       ".dockerignore",
+			want:       false,
+		},
+		{
+			name:       "directory of Dockerfile",
+			dockerfile: "docker/Dockerfile",
+			path:       "docker",
+			isDir:      true,
+			want:       false,
+		},
+		{
+			name:       "other file of the directory of Dockerfile",
+			dockerfile: "docker/Dockerfile",
+			path:       "docker/entrypoint.sh",
+			want:       true,
+		},
+		{
+			name:       "outside of context",
+			dockerfile: "Dockerfile",
+			path:       "../README.md",
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newDockerignoreFilter(context, filepath.Join(context, tt.dockerfile))
+			if err != nil {
+				t.Fatalf("newDockerignoreFilter() error = %v", err)
+			}
+			if got := f.Ignored(filepath.Join(context, tt.path), tt.isDir); got != tt.want {
+				t.Errorf("ignoreFilter.Ignored() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerignoreFilter_NoFile(t *testing.T) {
+	context, err := ioutil.TempDir("", "filter_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(context)
+	f, err := newDockerignoreFilter(context, filepath.Join(context, "Dockerfile"))
+	if err != nil || f != nil {
+		t.Errorf("newDockerignoreFilter() = %v, %v, want nil, nil", f, err)
+	}
+}
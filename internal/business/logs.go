@@ -0,0 +1,77 @@
+package business
+
+import (
+	"docker-compose-watcher/pkg/dockercompose"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// logsStopTimeout is how long 'logs' may take to exit after it was interrupted.
+const logsStopTimeout = 5 * time.Second
+
+// followLogs follows the logs of the project in detached mode, unless they are
+// already followed. When the logs are followed again, e.g. after all
+// containers had stopped, only new lines are shown.
+func (c *ComposeController) followLogs() error {
+	if c.logs != nil {
+		return nil
+	}
+	opt := dockercompose.LogsOptions{Follow: true}
+	if c.logsFollowed {
+		opt.Tail = "0"
+	}
+	l := c.cmd.Logs(opt)
+	l.Stdout = os.Stdout
+	l.Stderr = os.Stderr
+	p, err := c.startSupervised(l)
+	if err != nil {
+		return errors.Wrap(err, "failed to start docker compose logs")
+	}
+	c.logs = p
+	c.logsFollowed = true
+	return nil
+}
+
+// logsExited returns a channel that is closed when the followed logs end.
+func (c *ComposeController) logsExited() <-chan struct{} {
+	if c.logs == nil {
+		return nil
+	}
+	return c.logs.Done()
+}
+
+// stopLogs stops following the logs of the project.
+func (c *ComposeController) stopLogs() error {
+	if c.logs == nil {
+		return nil
+	}
+	p := c.logs
+	c.logs = nil
+	return p.Stop(logsStopTimeout)
+}
+
+// upDetached starts the containers of the project in the background and
+// follows their logs.
+func (c *ComposeController) upDetached() error {
+	u := c.cmd.Up(dockercompose.UpOptions{Detach: true})
+	u.Stdout = os.Stdout
+	u.Stderr = os.Stderr
+	if err := u.Run(); err != nil {
+		return errors.Wrap(err, "docker compose up failed")
+	}
+	return c.followLogs()
+}
+
+// stopContainers stops the containers of the project that were started in
+// detached mode.
+func (c *ComposeController) stopContainers() error {
+	s := c.cmd.Stop(dockercompose.StopOptions{})
+	s.Stdout = os.Stdout
+	s.Stderr = os.Stderr
+	if err := s.Run(); err != nil {
+		return errors.Wrap(err, "docker compose stop failed")
+	}
+	return nil
+}
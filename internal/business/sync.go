@@ -0,0 +1,89 @@
+package business
+
+import (
+	"docker-compose-watcher/pkg/dockercompose"
+	"docker-compose-watcher/pkg/ignore"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// syncOp copies a path into, or removes a path from, the container.
+type syncOp struct {
+	src    string
+	dst    string
+	remove bool
+}
+
+// syncOps returns the operations that sync the changes of sync targets into the
+// container of the service. It reports false if the service must be rebuilt
+// instead, which is when a path matches the rebuild patterns.
+func (c *ComposeController) syncOps(service string, changes []change) ([]syncOp, bool, error) {
+	rebuild, err := ignore.New(c.services[service].SyncRebuild)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "invalid rebuild patterns of service %v", service)
+	}
+	var ops []syncOp
+	index := make(map[string]int)
+	for _, v := range changes {
+		if !isSync(v.target.action) {
+			continue
+		}
+		p := v.msg.Path
+		dst := v.target.syncTarget
+		r := filepath.Base(p)
+		if !v.target.file {
+			r, err = filepath.Rel(v.target.path, p)
+			if err != nil {
+				return nil, false, errors.Wrap(err, "failed to get relative path")
+			}
+			r = filepath.ToSlash(r)
+			dst = path.Join(dst, r)
+		}
+		i, err := os.Stat(p)
+		exists := err == nil
+		if rebuild.Match(r, exists && i.IsDir()) {
+			return nil, false, nil
+		}
+		op := syncOp{
+			src:    p,
+			dst:    dst,
+			remove: !exists,
+		}
+		if exists && i.IsDir() {
+			// copy the contents, instead of the directory into an existing one
+			op.src = p + string(filepath.Separator) + "."
+		}
+		// only the latest operation on a path matters
+		if k, ok := index[p]; ok {
+			ops[k] = op
+			continue
+		}
+		index[p] = len(ops)
+		ops = append(ops, op)
+	}
+	return ops, true, nil
+}
+
+// syncService copies the changed paths into the running container of the service
+// and removes the deleted ones. It reports false if the service must be rebuilt instead.
+func (c *ComposeController) syncService(service string, changes []change) (bool, error) {
+	ops, ok, err := c.syncOps(service, changes)
+	if err != nil || !ok {
+		return false, err
+	}
+	for _, v := range ops {
+		e := c.cmd.Copy(dockercompose.CopyOptions{}, v.src, service, v.dst)
+		if v.remove {
+			e = c.cmd.Exec(dockercompose.ExecOptions{NoTTY: true}, service, "rm", "-rf", v.dst)
+		}
+		e.Stdout = os.Stdout
+		e.Stderr = os.Stderr
+		if err := e.Run(); err != nil {
+			return false, errors.Wrapf(err, "failed to sync %v to %v of %v", v.src, v.dst, service)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/provider/translator"
+	"docker-compose-watcher/internal/rlistener"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComposeController_syncOps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sync_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "pkg"), 0700); err != nil {
+		t.Fatalf("failed to create dirs: %v", err)
+	}
+	for _, v := range []string{"src/main.go", "src/package.json", "config.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, v), []byte("foo"), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	srcTarget := &watchTarget{service: "app", path: src, action: translator.ActionSync, syncTarget: "/app"}
+	fileTarget := &watchTarget{service: "app", path: filepath.Join(dir, "config.json"), file: true, action: translator.ActionSyncRestart, syncTarget: "/etc/app/config.json"}
+	buildTarget := &watchTarget{service: "app", path: dir, action: translator.ActionBuild}
+	changed := func(t *watchTarget, path string, op rlistener.Operation) change {
+		return change{rlistener.ListenerMsg{Path: filepath.Join(dir, path), Operation: op}, t}
+	}
+	tests := []struct {
+		name    string
+		rebuild []string
+		changes []change
+		want    []syncOp
+		wantOk  bool
+	}{
+		{
+			name:    "directory target",
+			changes: []change{changed(srcTarget, "src/main.go", rlistener.Write)},
+			want:    []syncOp{{src: filepath.Join(src, "main.go"), dst: "/app/main.go"}},
+			wantOk:  true,
+		},
+		{
+			name:    "changed directory",
+			changes: []change{changed(srcTarget, "src/pkg", rlistener.Create)},
+			want:    []syncOp{{src: filepath.Join(src, "pkg") + string(filepath.Separator) + ".", dst: "/app/pkg"}},
+			wantOk:  true,
+		},
+		{
+			name:    "file target",
+			changes: []change{changed(fileTarget, "config.json", rlistener.Write)},
+			want:    []syncOp{{src: filepath.Join(dir, "config.json"), dst: "/etc/app/config.json"}},
+			wantOk:  true,
+		},
+		{
+			name:    "deleted path",
+			changes: []change{changed(srcTarget, "src/gone.go", rlistener.Remove)},
+			want:    []syncOp{{src: filepath.Join(src, "gone.go"), dst: "/app/gone.go", remove: true}},
+			wantOk:  true,
+		},
+		{
+			name:    "rebuild patterns",
+			rebuild: []string{"package.json"},
+			changes: []change{
+				changed(srcTarget, "src/main.go", rlistener.Write),
+				changed(srcTarget, "src/package.json", rlistener.Write),
+			},
+			wantOk: false,
+		},
+		{
+			name: "path changed twice",
+			changes: []change{
+				changed(srcTarget, "src/main.go", rlistener.Create),
+				changed(srcTarget, "src/package.json", rlistener.Write),
+				changed(srcTarget, "src/main.go", rlistener.Write),
+			},
+			want: []syncOp{
+				{src: filepath.Join(src, "main.go"), dst: "/app/main.go"},
+				{src: filepath.Join(src, "package.json"), dst: "/app/package.json"},
+			},
+			wantOk: true,
+		},
+		{
+			name: "not synced",
+			changes: []change{
+				changed(buildTarget, "src/main.go", rlistener.Write),
+			},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ComposeController{
+				services: map[string]translator.WatchedService{
+					"app": {Name: "app", SyncRebuild: tt.rebuild},
+				},
+			}
+			got, ok, err := c.syncOps("app", tt.changes)
+			if err != nil {
+				t.Fatalf("ComposeController.syncOps() error = %v", err)
+			}
+			if ok != tt.wantOk {
+				t.Errorf("ComposeController.syncOps() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComposeController.syncOps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
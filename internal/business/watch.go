@@ -0,0 +1,163 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/provider/translator"
+	"docker-compose-watcher/internal/rlistener"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultDockerfile = "Dockerfile"
+
+// watchTarget is a directory or a file that is watched for changes of a service.
+type watchTarget struct {
+	service string
+	path    string
+	file    bool
+	filters []rlistener.Filter
+	// action is performed when the target changes
+	action translator.Action
+	// syncTarget is the path in the container that the target is synced to
+	syncTarget string
+}
+
+// change is a change of a watched target.
+type change struct {
+	msg    rlistener.ListenerMsg
+	target *watchTarget
+}
+
+func (t *watchTarget) matches(path string, isDir bool) bool {
+	if t.file {
+		return path == t.path
+	}
+	return rlistener.Contains(t.path, path) && !rlistener.IgnoredBy(t.filters, path, isDir)
+}
+
+// isRemoteContext reports whether the build context is a URL rather than a directory.
+func isRemoteContext(context string) bool {
+	return strings.Contains(context, "://") || strings.HasPrefix(context, "git@")
+}
+
+// dockerfilePath returns the absolute path of the service's Dockerfile, or an
+// empty string if the service is not built from a local context.
+func dockerfilePath(s translator.WatchedService) (string, error) {
+	if s.BuildContext == "" || isRemoteContext(s.BuildContext) {
+		return "", nil
+	}
+	df := s.Dockerfile
+	if df == "" {
+		df = defaultDockerfile
+	}
+	if !filepath.IsAbs(df) {
+		df = filepath.Join(s.Directory, s.BuildContext, df)
+	}
+	return filepath.Abs(df)
+}
+
+// newWatchTarget creates a target for a path relative to the service's directory.
+// The ignore patterns only apply if the path is a directory.
+func (c *ComposeController) newWatchTarget(s translator.WatchedService, path string, ignore []string) (watchTarget, error) {
+	p, err := filepath.Abs(filepath.Join(s.Directory, path))
+	if err != nil {
+		return watchTarget{}, errors.Wrapf(err, "failed to get absolute path of %v", path)
+	}
+	if i, err := os.Stat(p); err == nil && !i.IsDir() {
+		return watchTarget{service: s.Name, path: p, file: true}, nil
+	}
+	filters, err := c.serviceFilters(p, s, ignore)
+	if err != nil {
+		return watchTarget{}, err
+	}
+	return watchTarget{service: s.Name, path: p, filters: filters}, nil
+}
+
+// developTargets returns the targets of the rules in the develop.watch section.
+func (c *ComposeController) developTargets(s translator.WatchedService) ([]watchTarget, error) {
+	var targets []watchTarget
+	for _, v := range s.Watch {
+		t, err := c.newWatchTarget(s, v.Path, v.Ignore)
+		if err != nil {
+			return nil, err
+		}
+		t.action = v.Action
+		t.syncTarget = v.Target
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// labelTargets returns the targets of the paths in the labels, or the build
+// context if paths are inferred. The Dockerfile is watched too if it is outside of them.
+func (c *ComposeController) labelTargets(s translator.WatchedService) ([]watchTarget, error) {
+	paths := s.Paths
+	if len(paths) == 0 && c.inferPaths && s.BuildContext != "" && !isRemoteContext(s.BuildContext) {
+		paths = []string{s.BuildContext}
+	}
+	var targets []watchTarget
+	for _, v := range paths {
+		t, err := c.newWatchTarget(s, v, s.Ignore)
+		if err != nil {
+			return nil, err
+		}
+		t.action = s.Action
+		t.syncTarget = s.SyncTarget
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	df, err := dockerfilePath(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get absolute path of the Dockerfile of %v", s.Name)
+	}
+	if df == "" {
+		return targets, nil
+	}
+	for _, v := range targets {
+		if rlistener.Contains(v.path, df) {
+			return targets, nil
+		}
+	}
+	if _, err := os.Stat(df); err != nil {
+		return targets, nil
+	}
+	return append(targets, watchTarget{
+		service: s.Name,
+		path:    df,
+		file:    true,
+		action:  translator.ActionBuild,
+	}), nil
+}
+
+// watchTargets returns the targets to watch for the service. The rules of the
+// develop.watch section are used if there are any, otherwise the labels are.
+// Services that are not active under the selected profiles are not watched.
+func (c *ComposeController) watchTargets(s translator.WatchedService) ([]watchTarget, error) {
+	if !s.Enabled || !s.Active(c.profiles) {
+		return nil, nil
+	}
+	if len(s.Watch) > 0 {
+		return c.developTargets(s)
+	}
+	return c.labelTargets(s)
+}
+
+// changedServices groups the changes by the services of the targets watching their paths.
+func changedServices(targets []watchTarget, msgs ...rlistener.ListenerMsg) map[string][]change {
+	changes := make(map[string][]change)
+	for _, m := range msgs {
+		i, err := os.Lstat(m.Path)
+		isDir := err == nil && i.IsDir()
+		for k := range targets {
+			t := &targets[k]
+			if t.matches(m.Path, isDir) {
+				changes[t.service] = append(changes[t.service], change{m, t})
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,86 @@
+package business
+
+import (
+	"docker-compose-watcher/internal/rlistener"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangedServices(t *testing.T) {
+	root := filepath.FromSlash("/mnt/src")
+	logs, err := newIgnoreFilter(filepath.Join(root, "api"), []string{"*.log"})
+	if err != nil {
+		t.Fatalf("newIgnoreFilter() error = %v", err)
+	}
+	targets := []watchTarget{
+		{service: "api", path: filepath.Join(root, "api"), filters: []rlistener.Filter{logs}},
+		{service: "api2", path: filepath.Join(root, "api2")},
+		{service: "all", path: root},
+		{service: "config", path: filepath.Join(root, "config.yml"), file: true},
+	}
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "nested roots",
+			path: "api/main.go",
+			want: []string{"all", "api"},
+		},
+		{
+			name: "sibling prefix",
+			path: "api2/main.go",
+			want: []string{"all", "api2"},
+		},
+		{
+			name: "root itself",
+			path: "api",
+			want: []string{"all", "api"},
+		},
+		{
+			name: "file target",
+			path: "config.yml",
+			want: []string{"all", "config"},
+		},
+		{
+			name: "prefix of file target",
+			path: "config.yml.bak",
+			want: []string{"all"},
+		},
+		{
+			name: "ignored by a target",
+			path: "api/debug.log",
+			want: []string{"all"},
+		},
+		{
+			name: "outside of targets",
+			path: "../other/main.go",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(root, filepath.FromSlash(tt.path))
+			changes := changedServices(targets, rlistener.ListenerMsg{Path: p, Operation: rlistener.Write})
+			var got []string
+			for _, k := range []string{"all", "api", "api2", "config"} {
+				v, ok := changes[k]
+				if !ok {
+					continue
+				}
+				got = append(got, k)
+				if len(v) != 1 || v[0].msg.Path != p || v[0].target.service != k {
+					t.Errorf("changedServices()[%v] = %v, want change of %v", k, v, p)
+				}
+			}
+			if len(changes) != len(got) {
+				t.Errorf("changedServices() = %v, want services %v", changes, tt.want)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedServices() services = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
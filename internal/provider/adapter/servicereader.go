@@ -26,3 +26,19 @@ func (r *readerImpl) Read() (provider.ReaderValue, error) {
 func NewServiceReader() (provider.Reader, error) {
 	return &readerImpl{service.NewReader()}, nil
 }
+
+// NewServiceReaderWithEnvFile returns a factory of docker compose service readers
+// that interpolate the variables of the env file, or of the project's .env file if empty.
+func NewServiceReaderWithEnvFile(envFile string) provider.ReaderFactoryFunc {
+	return func() (provider.Reader, error) {
+		return &readerImpl{service.NewReaderWithEnvFile(envFile)}, nil
+	}
+}
+
+// NewServiceReaderFrom returns a factory of a docker compose service reader
+// that reads with r, so that the caller can inspect the files that r has read.
+func NewServiceReaderFrom(r *service.Reader) provider.ReaderFactoryFunc {
+	return func() (provider.Reader, error) {
+		return &readerImpl{r}, nil
+	}
+}
@@ -4,28 +4,195 @@ import (
 	"docker-compose-watcher/pkg/dockercompose/service"
 	"docker-compose-watcher/pkg/flatmapper"
 	"docker-compose-watcher/pkg/provider"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 const labelTag = "dcw"
 
+var logWarningf = func(format string, v ...interface{}) {
+	log.Printf("warning: "+format, v...)
+}
+
+// Actions
+const (
+	// ActionBuild rebuilds the image and recreates the container.
+	ActionBuild = Action("build")
+	// ActionRestart restarts the container without rebuilding it.
+	ActionRestart = Action("restart")
+	// ActionExec runs a command in the running container.
+	ActionExec = Action("exec")
+	// ActionSync copies changed files into the running container.
+	ActionSync = Action("sync")
+	// ActionSyncRestart copies changed files into the running container and restarts it.
+	ActionSyncRestart = Action("sync+restart")
+	// ActionNone does nothing.
+	ActionNone = Action("none")
+)
+
+// Action is what is done when the source of a service changes.
+type Action string
+
+func (a Action) valid() bool {
+	switch a {
+	case ActionBuild, ActionRestart, ActionExec, ActionSync, ActionSyncRestart, ActionNone:
+		return true
+	}
+	return false
+}
+
+// developActions maps the actions of the develop.watch section to actions.
+var developActions = map[string]Action{
+	"rebuild":      ActionBuild,
+	"restart":      ActionRestart,
+	"sync":         ActionSync,
+	"sync+restart": ActionSyncRestart,
+}
+
+// WatchRule is a path that is watched with its own action.
+type WatchRule struct {
+	// Path is relative to the service's Directory.
+	Path   string
+	Action Action
+	// Target is the path in the container that ActionSync copies the paths to.
+	Target string
+	// Ignore are gitignore-style patterns of paths to ignore, relative to Path.
+	Ignore []string
+}
+
 // WatchedService is a service that is provided by the Provider.
 type WatchedService struct {
 	Name      string
 	Directory string
-	Path      string `dcw:"docker-compose-watcher.path"`
+	// BuildContext is the build context relative to Directory, if the service is built.
+	BuildContext string
+	// Dockerfile is the Dockerfile relative to BuildContext, or empty for the default.
+	Dockerfile string
+	// Watch are the rules of the develop.watch section, which take precedence
+	// over the paths and actions in the labels.
+	Watch []WatchRule
+	// DependsOn are the services that the service depends on.
+	DependsOn []string
+	// Dependents are the services that depend on the service.
+	Dependents []string
+	// Profiles are the profiles that the service is enabled in, or empty if
+	// the service is always enabled.
+	Profiles []string
+	// Paths are the source directories, relative to Directory.
+	Paths []string `dcw:"docker-compose-watcher.path"`
+	// Enabled is false if the service should not be watched at all.
+	Enabled bool `dcw:"docker-compose-watcher.enabled"`
+	// Action defaults to ActionExec if Exec is set, to ActionSync if SyncTarget
+	// is set, and to ActionBuild otherwise.
+	Action Action `dcw:"docker-compose-watcher.action"`
+	// Exec is the shell command that ActionExec runs in the container.
+	Exec        string   `dcw:"docker-compose-watcher.exec"`
+	ExecUser    string   `dcw:"docker-compose-watcher.exec.user"`
+	ExecWorkdir string   `dcw:"docker-compose-watcher.exec.workdir"`
+	ExecEnv     []string `dcw:"docker-compose-watcher.exec.env"`
+	// SyncTarget is the path in the container that ActionSync copies the paths to.
+	SyncTarget string `dcw:"docker-compose-watcher.sync.target"`
+	// SyncRebuild are gitignore-style patterns of paths that are not synced,
+	// but rebuild the service instead.
+	SyncRebuild []string `dcw:"docker-compose-watcher.sync.rebuild"`
+	// Ignore are gitignore-style patterns of paths to ignore.
+	Ignore []string `dcw:"docker-compose-watcher.ignore"`
+	// RestartDependents restarts the services that depend on the service,
+	// directly or transitively, after it was rebuilt.
+	RestartDependents bool `dcw:"docker-compose-watcher.restart-dependents"`
+	// Debounce is how long the changes of the service must settle before they
+	// are acted on, or zero for the global default.
+	Debounce time.Duration `dcw:"docker-compose-watcher.debounce"`
+}
+
+// Active reports whether the service is enabled under the selected profiles,
+// where the profile '*' selects all profiles.
+func (s WatchedService) Active(profiles []string) bool {
+	if len(s.Profiles) == 0 {
+		return true
+	}
+	for _, v := range profiles {
+		if v == "*" {
+			return true
+		}
+		for _, p := range s.Profiles {
+			if v == p {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func translate(src map[string]service.LabelledService) map[string]WatchedService {
+func translateWatch(src []service.WatchRule) ([]WatchRule, error) {
+	var rules []WatchRule
+	for _, v := range src {
+		if v.Path == "" {
+			return nil, errors.New("develop.watch rule requires a path")
+		}
+		a, ok := developActions[v.Action]
+		if !ok {
+			// rules that docker compose watch supports should not stop the watcher
+			logWarningf("skipping develop.watch rule of %s with unsupported action '%s'", v.Path, v.Action)
+			continue
+		}
+		if (a == ActionSync || a == ActionSyncRestart) && v.Target == "" {
+			return nil, errors.Errorf("develop.watch action '%s' requires a target", v.Action)
+		}
+		rules = append(rules, WatchRule{
+			Path:   v.Path,
+			Action: a,
+			Target: v.Target,
+			Ignore: v.Ignore,
+		})
+	}
+	return rules, nil
+}
+
+func translate(src map[string]service.LabelledService) (map[string]WatchedService, error) {
 	m := make(map[string]WatchedService, len(src))
 	for k, v := range src {
-		v := flatmapper.MapToStruct(labelTag, v.Labels, &WatchedService{
-			Name:      v.Name,
-			Directory: v.Directory,
+		w, err := flatmapper.MapToStruct(labelTag, v.Labels, &WatchedService{
+			Name:         v.Name,
+			Directory:    v.Directory,
+			BuildContext: v.Build.Context,
+			Dockerfile:   v.Build.Dockerfile,
+			DependsOn:    v.DependsOn,
+			Dependents:   v.Dependents,
+			Profiles:     v.Profiles,
+			Enabled:      true,
 		})
-		s := *v.(*WatchedService)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid labels of service %s", v.Name)
+		}
+		s := *w.(*WatchedService)
+		if s.Action == "" {
+			switch {
+			case s.Exec != "":
+				s.Action = ActionExec
+			case s.SyncTarget != "":
+				s.Action = ActionSync
+			default:
+				s.Action = ActionBuild
+			}
+		}
+		if !s.Action.valid() {
+			return nil, errors.Errorf("invalid action '%s' of service %s", s.Action, v.Name)
+		}
+		if s.Action == ActionExec && s.Exec == "" {
+			return nil, errors.Errorf("action '%s' of service %s requires a command", s.Action, v.Name)
+		}
+		if (s.Action == ActionSync || s.Action == ActionSyncRestart) && s.SyncTarget == "" {
+			return nil, errors.Errorf("action '%s' of service %s requires a target", s.Action, v.Name)
+		}
+		if s.Watch, err = translateWatch(v.Watch); err != nil {
+			return nil, errors.Wrapf(err, "invalid develop.watch of service %s", v.Name)
+		}
 		m[k] = s
 	}
-	return m
+	return m, nil
 }
 
 // NewServiceTranslatorChannel creates a new channel that translates LabelledService
@@ -44,8 +211,10 @@ func NewServiceTranslatorChannel(src <-chan provider.ReaderValueWithError) <-cha
 				}
 				continue
 			}
+			m, err := translate(v.Value.(map[string]service.LabelledService))
 			dst <- provider.ReaderValueWithError{
-				Value: translate(v.Value.(map[string]service.LabelledService)),
+				Value: m,
+				Error: err,
 			}
 		}
 		close(dst)
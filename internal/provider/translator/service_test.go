@@ -0,0 +1,217 @@
+package translator
+
+import (
+	"docker-compose-watcher/pkg/dockercompose/service"
+	"reflect"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		build    service.Build
+		watch    []service.WatchRule
+		want     WatchedService
+		wantErr  bool
+		wantWarn bool
+	}{
+		{
+			name:  "defaults to build",
+			build: service.Build{Context: "./app", Dockerfile: "dev.Dockerfile"},
+			want: WatchedService{
+				BuildContext: "./app",
+				Dockerfile:   "dev.Dockerfile",
+				Enabled:      true,
+				Action:       ActionBuild,
+			},
+		},
+		{
+			name: "labels",
+			labels: map[string]string{
+				"docker-compose-watcher.path":    "./src, ./lib",
+				"docker-compose-watcher.enabled": "false",
+				"docker-compose-watcher.ignore":  "*.log",
+			},
+			want: WatchedService{
+				Paths:  []string{"./src", "./lib"},
+				Action: ActionBuild,
+				Ignore: []string{"*.log"},
+			},
+		},
+		{
+			name: "defaults to exec with a command",
+			labels: map[string]string{
+				"docker-compose-watcher.exec":        "kill -HUP 1",
+				"docker-compose-watcher.sync.target": "/app",
+			},
+			want: WatchedService{
+				Enabled:    true,
+				Action:     ActionExec,
+				Exec:       "kill -HUP 1",
+				SyncTarget: "/app",
+			},
+		},
+		{
+			name: "defaults to sync with a target",
+			labels: map[string]string{
+				"docker-compose-watcher.sync.target": "/app",
+			},
+			want: WatchedService{
+				Enabled:    true,
+				Action:     ActionSync,
+				SyncTarget: "/app",
+			},
+		},
+		{
+			name: "explicit action",
+			labels: map[string]string{
+				"docker-compose-watcher.action":      "restart",
+				"docker-compose-watcher.sync.target": "/app",
+			},
+			want: WatchedService{
+				Enabled:    true,
+				Action:     ActionRestart,
+				SyncTarget: "/app",
+			},
+		},
+		{
+			name: "invalid action",
+			labels: map[string]string{
+				"docker-compose-watcher.action": "reload",
+			},
+			wantErr: true,
+		},
+		{
+			name: "exec without command",
+			labels: map[string]string{
+				"docker-compose-watcher.action": "exec",
+			},
+			wantErr: true,
+		},
+		{
+			name: "sync without target",
+			labels: map[string]string{
+				"docker-compose-watcher.action": "sync",
+			},
+			wantErr: true,
+		},
+		{
+			name: "sync+restart without target",
+			labels: map[string]string{
+				"docker-compose-watcher.action": "sync+restart",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid label value",
+			labels: map[string]string{
+				"docker-compose-watcher.debounce": "soon",
+			},
+			wantErr: true,
+		},
+		{
+			name: "develop.watch",
+			watch: []service.WatchRule{
+				{Action: "rebuild", Path: "./package.json"},
+				{Action: "sync", Path: "./src", Target: "/app/src", Ignore: []string{"node_modules/"}},
+				{Action: "sync+restart", Path: "./config", Target: "/etc/app"},
+				{Action: "restart", Path: "./.env"},
+			},
+			want: WatchedService{
+				Enabled: true,
+				Action:  ActionBuild,
+				Watch: []WatchRule{
+					{Path: "./package.json", Action: ActionBuild},
+					{Path: "./src", Action: ActionSync, Target: "/app/src", Ignore: []string{"node_modules/"}},
+					{Path: "./config", Action: ActionSyncRestart, Target: "/etc/app"},
+					{Path: "./.env", Action: ActionRestart},
+				},
+			},
+		},
+		{
+			name: "develop.watch unsupported action",
+			watch: []service.WatchRule{
+				{Action: "sync+exec", Path: "./src", Target: "/app/src"},
+				{Action: "rebuild", Path: "./package.json"},
+			},
+			want: WatchedService{
+				Enabled: true,
+				Action:  ActionBuild,
+				Watch: []WatchRule{
+					{Path: "./package.json", Action: ActionBuild},
+				},
+			},
+			wantWarn: true,
+		},
+		{
+			name:    "develop.watch without path",
+			watch:   []service.WatchRule{{Action: "rebuild"}},
+			wantErr: true,
+		},
+		{
+			name:    "develop.watch sync without target",
+			watch:   []service.WatchRule{{Action: "sync", Path: "./src"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldLogWarningf := logWarningf
+			warned := false
+			logWarningf = func(format string, v ...interface{}) {
+				warned = true
+			}
+			defer func() {
+				logWarningf = oldLogWarningf
+			}()
+			got, err := translate(map[string]service.LabelledService{
+				"app": {
+					Name:      "app",
+					Directory: "/mnt/x",
+					Labels:    tt.labels,
+					Build:     tt.build,
+					Watch:     tt.watch,
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("translate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.want.Name = "app"
+			tt.want.Directory = "/mnt/x"
+			if !reflect.DeepEqual(got["app"], tt.want) {
+				t.Errorf("translate() = %+v, want %+v", got["app"], tt.want)
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("translate() warned = %v, wantWarn %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestWatchedService_Active(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		selected []string
+		want     bool
+	}{
+		{"without profiles", nil, nil, true},
+		{"without profiles and selected profiles", nil, []string{"debug"}, true},
+		{"no selected profiles", []string{"debug"}, nil, false},
+		{"selected profile", []string{"debug", "test"}, []string{"test"}, true},
+		{"other selected profile", []string{"debug"}, []string{"test"}, false},
+		{"all profiles", []string{"debug"}, []string{"*"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := WatchedService{Name: "app", Profiles: tt.profiles}
+			if got := s.Active(tt.selected); got != tt.want {
+				t.Errorf("WatchedService.Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
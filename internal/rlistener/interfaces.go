@@ -26,3 +26,9 @@ type Watcher interface {
 	Channel() <-chan WatcherMsg
 	Close() error
 }
+
+// Filter decides which paths the listener ignores.
+type Filter interface {
+	// Ignored reports whether the absolute path is ignored.
+	Ignored(path string, isDir bool) bool
+}
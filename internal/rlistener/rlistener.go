@@ -9,12 +9,17 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Listener recursively listens for changes within added directories.
+// Listener recursively listens for changes within added directories, and for
+// changes to added files.
 type Listener struct {
 	w   Watcher
 	ch  chan ListenerMsg
 	mtx sync.Mutex
 	ld  map[string][]string
+	// lf are the filters of each AddDir call of the directories
+	lf map[string][][]Filter
+	// files are the watched files, whose parent directories are watched
+	files map[string]struct{}
 }
 
 // ListenerMsg is a message from the listener.
@@ -27,8 +32,10 @@ type ListenerMsg struct {
 // WatcherFactoryFunc is a function for creting watchers for the listener.
 type WatcherFactoryFunc func() (Watcher, error)
 
-// AddDir adds a directory to listen on.
-func (l *Listener) AddDir(path string) error {
+// AddDir adds a directory to listen on. Paths ignored by any of the filters
+// are neither watched nor reported, unless another AddDir call of the same
+// directory does not ignore them.
+func (l *Listener) AddDir(path string, filters ...Filter) error {
 	i, err := os.Stat(path)
 	if err != nil {
 		return errors.Wrapf(err, "stat failed on file %s", path)
@@ -40,12 +47,36 @@ func (l *Listener) AddDir(path string) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to get absolute path of %s", path)
 	}
+	l.mtx.Lock()
+	l.lf[path] = append(l.lf[path], filters)
+	l.mtx.Unlock()
 	if err := l.recursiveDiscover(path); err != nil {
 		return err
 	}
 	return l.w.AddDir(path)
 }
 
+// AddFile adds a file to listen on. The parent directory of the file is watched,
+// so that the file is still reported after it has been replaced, e.g. by an
+// editor writing to a temporary file and renaming it.
+func (l *Listener) AddFile(path string) error {
+	i, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "stat failed on file %s", path)
+	}
+	if i.IsDir() {
+		return errors.New("the path pointed to a directory")
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get absolute path of %s", path)
+	}
+	l.mtx.Lock()
+	l.files[path] = struct{}{}
+	l.mtx.Unlock()
+	return l.w.AddDir(filepath.Dir(path))
+}
+
 // Channel returns the listener's channel.
 func (l *Listener) Channel() <-chan ListenerMsg {
 	return l.ch
@@ -100,7 +131,42 @@ func (l *Listener) applyDiff(path string, add []string, rem []string) error {
 	return nil
 }
 
+// IgnoredBy reports whether the path is ignored by any of the filters.
+func IgnoredBy(filters []Filter, path string, isDir bool) bool {
+	for _, v := range filters {
+		if v.Ignored(path, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredByAll reports whether the path is ignored by every set of filters.
+func ignoredByAll(sets [][]Filter, path string, isDir bool) bool {
+	for _, filters := range sets {
+		if !IgnoredBy(filters, path, isDir) {
+			return false
+		}
+	}
+	return len(sets) > 0
+}
+
+func isDir(path string) bool {
+	i, err := os.Lstat(path)
+	return err == nil && i.IsDir()
+}
+
+// Contains reports whether the path is the directory or a path below it.
+func Contains(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// recursiveDiscover watches the directories below path that are not ignored and
+// stops watching the ones that are gone.
 func (l *Listener) recursiveDiscover(path string) error {
+	l.mtx.Lock()
+	sets := l.lf[path]
+	l.mtx.Unlock()
 	var dst []string
 	err := filepath.Walk(path, func(fpath string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -109,6 +175,9 @@ func (l *Listener) recursiveDiscover(path string) error {
 		if !info.IsDir() {
 			return nil
 		}
+		if fpath != path && ignoredByAll(sets, fpath, true) {
+			return filepath.SkipDir
+		}
 		r, err := filepath.Rel(path, fpath)
 		if err != nil {
 			return errors.Wrap(err, "failed to get relative path")
@@ -130,12 +199,28 @@ func (l *Listener) recursiveDiscover(path string) error {
 	return nil
 }
 
-func (l *Listener) handleMsg(w ListenerMsg) {
+// handleMsg rediscovers the roots containing the message's path. It reports
+// whether the path is neither a watched file nor in a root that does not ignore it.
+func (l *Listener) handleMsg(m ListenerMsg) bool {
+	l.mtx.Lock()
+	roots := make(map[string][][]Filter)
 	for k := range l.ld {
-		if strings.HasPrefix(w.Path, k) {
-			l.recursiveDiscover(k)
+		if Contains(k, m.Path) {
+			roots[k] = l.lf[k]
+		}
+	}
+	_, file := l.files[m.Path]
+	l.mtx.Unlock()
+	dir := isDir(m.Path)
+	ignored := !file
+	for k, sets := range roots {
+		if ignoredByAll(sets, m.Path, dir) {
+			continue
 		}
+		ignored = false
+		l.recursiveDiscover(k)
 	}
+	return ignored
 }
 
 func (l *Listener) run() {
@@ -151,7 +236,7 @@ func (l *Listener) run() {
 		}
 		ap, err := filepath.Abs(w.Path)
 		if err != nil {
-			l.ch <- ListenerMsg{Error: w.Err}
+			l.ch <- ListenerMsg{Error: err}
 			continue
 		}
 		m := ListenerMsg{
@@ -159,8 +244,9 @@ func (l *Listener) run() {
 			Operation: w.Op,
 			Error:     w.Err,
 		}
-		l.handleMsg(m)
-		l.ch <- m
+		if !l.handleMsg(m) {
+			l.ch <- m
+		}
 	}
 	close(l.ch)
 }
@@ -176,9 +262,11 @@ func New(watcherFactory WatcherFactoryFunc) (*Listener, error) {
 		return nil, errors.Wrap(err, "failed to create watcher")
 	}
 	l := &Listener{
-		w:  w,
-		ch: make(chan ListenerMsg),
-		ld: make(map[string][]string),
+		w:     w,
+		ch:    make(chan ListenerMsg),
+		ld:    make(map[string][]string),
+		lf:    make(map[string][][]Filter),
+		files: make(map[string]struct{}),
 	}
 	go l.run()
 	return l, nil
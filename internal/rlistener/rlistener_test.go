@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type TExtended testing.T
@@ -26,6 +27,14 @@ func (t *TExtended) fatalIfErr(err error, msg string) {
 	}
 }
 
+func closeOnce(c chan struct{}) {
+	select {
+	case <-c:
+	default:
+		close(c)
+	}
+}
+
 func TestListenerWithWatchers(t *testing.T) {
 	f := map[string]rlistener.WatcherFactoryFunc{
 		"fsnotify": fsnotify.New,
@@ -61,7 +70,10 @@ func TestListenerWithWatchers(t *testing.T) {
 			tt.errorIfErr(l.AddDir(path), "Listener.AddDir()")
 
 			var got []rlistener.ListenerMsg
+			ap := filepath.Join(path, "/0/1/2/3/4/5/6/7/8/9/10/11/12/13/14/15/16")
+			synced := filepath.Join(path, "synced")
 			n := make(chan struct{})
+			written := make(chan struct{})
 			done := make(chan struct{})
 			go func() {
 				c := l.Channel()
@@ -71,21 +83,29 @@ func TestListenerWithWatchers(t *testing.T) {
 						break
 					}
 					got = append(got, m)
-					select {
-					case n <- struct{}{}:
-					default:
+					// every message rediscovers the root, so the directories
+					// created before the synced file are watched once it is sent
+					if m.Path == synced {
+						closeOnce(n)
+					}
+					if m.Path == filepath.Join(ap, "file") && m.Operation == rlistener.Write {
+						closeOnce(written)
 					}
 				}
 				done <- struct{}{}
 			}()
-			ap := filepath.Join(path, "/0/1/2/3/4/5/6/7/8/9/10/11/12/13/14/15/16")
 			tt.errorIfErr(os.MkdirAll(ap, 0700), "failed to create test directories")
 			for k := range want {
 				want[k].Path = filepath.Join(path, want[k].Path)
 			}
+			tt.errorIfErr(ioutil.WriteFile(synced, []byte("foo"), 0700), "failed to write to file")
 			<-n
 			err = ioutil.WriteFile(filepath.Join(ap, "file"), []byte("foo"), 0700)
 			tt.errorIfErr(err, "failed to write to file")
+			select {
+			case <-written:
+			case <-time.After(5 * time.Second):
+			}
 			tt.errorIfErr(
 				os.RemoveAll(filepath.Join(path, "/0/1")),
 				"failed to remove subdirs",
@@ -106,3 +126,164 @@ func TestListenerWithWatchers(t *testing.T) {
 		})
 	}
 }
+
+type filterFunc func(path string, isDir bool) bool
+
+func (f filterFunc) Ignored(path string, isDir bool) bool {
+	return f(path, isDir)
+}
+
+func TestListenerFilters(t *testing.T) {
+	tt := (*TExtended)(t)
+	l, err := rlistener.New(fsnotify.New)
+	tt.fatalIfErr(err, "New()")
+
+	path, err := ioutil.TempDir("", "rlistener_test")
+	tt.fatalIfErr(err, "failed to create temp dir")
+	defer func() {
+		tt.fatalIfErr(os.RemoveAll(path), "failed to remove temp dir")
+	}()
+	ignored := filepath.Join(path, "ignored")
+	tt.fatalIfErr(os.Mkdir(ignored, 0700), "failed to create ignored dir")
+	filter := filterFunc(func(p string, isDir bool) bool {
+		return p == ignored || filepath.Ext(p) == ".swp"
+	})
+	tt.errorIfErr(l.AddDir(path, filter), "Listener.AddDir()")
+
+	kept := filepath.Join(path, "kept")
+	var got []rlistener.ListenerMsg
+	n := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		for m := range l.Channel() {
+			got = append(got, m)
+			if m.Path == kept {
+				closeOnce(n)
+			}
+		}
+		close(done)
+	}()
+	tt.errorIfErr(ioutil.WriteFile(filepath.Join(ignored, "file"), []byte("foo"), 0700), "failed to write ignored file")
+	tt.errorIfErr(ioutil.WriteFile(filepath.Join(path, ".file.swp"), []byte("foo"), 0700), "failed to write swap file")
+	tt.errorIfErr(ioutil.WriteFile(kept, []byte("foo"), 0700), "failed to write kept file")
+	select {
+	case <-n:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Listener.Channel() %v was not sent", kept)
+	}
+	tt.errorIfErr(l.Close(), "Listener.Close()")
+	<-done
+	for _, v := range got {
+		if v.Path != kept {
+			t.Errorf("Listener.Channel() sent ignored %v", v)
+		}
+	}
+}
+
+func TestListenerFiltersOfSameDir(t *testing.T) {
+	tt := (*TExtended)(t)
+	l, err := rlistener.New(fsnotify.New)
+	tt.fatalIfErr(err, "New()")
+
+	path, err := ioutil.TempDir("", "rlistener_test")
+	tt.fatalIfErr(err, "failed to create temp dir")
+	defer func() {
+		tt.fatalIfErr(os.RemoveAll(path), "failed to remove temp dir")
+	}()
+	a := filepath.Join(path, "a")
+	b := filepath.Join(path, "b")
+	tt.fatalIfErr(os.Mkdir(a, 0700), "failed to create dir")
+	tt.fatalIfErr(os.Mkdir(b, 0700), "failed to create dir")
+	// each filter ignores a directory that the other one does not
+	ignoring := func(dir string) filterFunc {
+		return func(p string, isDir bool) bool {
+			return p == dir || filepath.Ext(p) == ".swp"
+		}
+	}
+	tt.errorIfErr(l.AddDir(path, ignoring(a)), "Listener.AddDir()")
+	tt.errorIfErr(l.AddDir(path, ignoring(b)), "Listener.AddDir()")
+
+	files := []string{filepath.Join(a, "file"), filepath.Join(b, "file")}
+	sent := make(map[string]bool)
+	var got []rlistener.ListenerMsg
+	n := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		for m := range l.Channel() {
+			got = append(got, m)
+			sent[m.Path] = true
+			if sent[files[0]] && sent[files[1]] {
+				closeOnce(n)
+			}
+		}
+		close(done)
+	}()
+	tt.errorIfErr(ioutil.WriteFile(filepath.Join(path, ".file.swp"), []byte("foo"), 0700), "failed to write swap file")
+	for _, v := range files {
+		tt.errorIfErr(ioutil.WriteFile(v, []byte("foo"), 0700), "failed to write file")
+	}
+	select {
+	case <-n:
+	case <-time.After(5 * time.Second):
+	}
+	tt.errorIfErr(l.Close(), "Listener.Close()")
+	<-done
+	for _, v := range files {
+		if !sent[v] {
+			t.Errorf("Listener.Channel() %v was not sent", v)
+		}
+	}
+	for _, v := range got {
+		if filepath.Ext(v.Path) == ".swp" {
+			t.Errorf("Listener.Channel() sent ignored %v", v)
+		}
+	}
+}
+
+func TestListenerFiles(t *testing.T) {
+	tt := (*TExtended)(t)
+	l, err := rlistener.New(fsnotify.New)
+	tt.fatalIfErr(err, "New()")
+
+	path, err := ioutil.TempDir("", "rlistener_test")
+	tt.fatalIfErr(err, "failed to create temp dir")
+	defer func() {
+		tt.fatalIfErr(os.RemoveAll(path), "failed to remove temp dir")
+	}()
+	f := filepath.Join(path, "Dockerfile")
+	tt.fatalIfErr(ioutil.WriteFile(f, []byte("FROM foo"), 0700), "failed to write file")
+	if err := l.AddFile(path); err == nil {
+		t.Errorf("Listener.AddFile() of directory error = nil, want error")
+	}
+	tt.errorIfErr(l.AddFile(f), "Listener.AddFile()")
+
+	var got []rlistener.ListenerMsg
+	n := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		for m := range l.Channel() {
+			got = append(got, m)
+			if m.Path == f && m.Operation == rlistener.Create {
+				closeOnce(n)
+			}
+		}
+		close(done)
+	}()
+	tt.errorIfErr(ioutil.WriteFile(filepath.Join(path, "other"), []byte("foo"), 0700), "failed to write other file")
+	// editors save atomically by writing a temporary file and renaming it
+	tmp := filepath.Join(path, ".Dockerfile.tmp")
+	tt.errorIfErr(ioutil.WriteFile(tmp, []byte("FROM bar"), 0700), "failed to write temp file")
+	tt.errorIfErr(os.Rename(tmp, f), "failed to rename temp file")
+	select {
+	case <-n:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Listener.Channel() create of %v was not sent", f)
+	}
+	tt.errorIfErr(l.Close(), "Listener.Close()")
+	<-done
+	for _, v := range got {
+		if v.Path != f {
+			t.Errorf("Listener.Channel() sent unwatched %v", v)
+		}
+	}
+}
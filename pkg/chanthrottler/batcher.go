@@ -0,0 +1,43 @@
+package chanthrottler
+
+import "time"
+
+type batch[T any, K comparable] struct {
+	key   func(T) K
+	items []T
+	index map[K]int
+}
+
+func (b *batch[T, K]) add(v T) {
+	k := b.key(v)
+	if i, ok := b.index[k]; ok {
+		b.items[i] = v
+		return
+	}
+	b.index[k] = len(b.items)
+	b.items = append(b.items, v)
+}
+
+func (b *batch[T, K]) flush() []T {
+	items := b.items
+	b.items = nil
+	b.index = make(map[K]int)
+	return items
+}
+
+// Batcher reads values from a channel and emits all values that were received
+// until no value was received for a wait duration. Values with the same key
+// are only emitted once, in the order they were first received, with the
+// latest value of the key.
+type Batcher[T any] struct {
+	*debouncer[T, []T]
+}
+
+// NewBatcher creates a batcher that reads the values of ch until it is closed
+// or the batcher is stopped. Values are told apart by the result of key.
+func NewBatcher[T any, K comparable](ch <-chan T, wait time.Duration, opt Options, key func(T) K) *Batcher[T] {
+	return &Batcher[T]{newDebouncer[T, []T](ch, wait, opt, &batch[T, K]{
+		key:   key,
+		index: make(map[K]int),
+	})}
+}
@@ -0,0 +1,60 @@
+package chanthrottler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	const wait = 32 * time.Millisecond
+	type msg struct {
+		path string
+		op   int
+	}
+	key := func(m msg) string {
+		return m.path
+	}
+	tests := []struct {
+		name string
+		f    func(chan msg)
+		want [][]msg
+	}{
+		{
+			name: "push distinct values",
+			f: func(c chan msg) {
+				c <- msg{"a", 1}
+				c <- msg{"b", 1}
+				c <- msg{"a", 2}
+				c <- msg{"c", 1}
+				close(c)
+			},
+			want: [][]msg{{{"a", 2}, {"b", 1}, {"c", 1}}},
+		},
+		{
+			name: "push a batch per burst",
+			f: func(c chan msg) {
+				c <- msg{"a", 1}
+				c <- msg{"b", 1}
+				time.Sleep(4 * wait)
+				c <- msg{"a", 1}
+				close(c)
+			},
+			want: [][]msg{{{"a", 1}, {"b", 1}}, {{"a", 1}}},
+		},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(tt *testing.T) {
+			src := make(chan msg)
+			b := NewBatcher(src, 2*wait, Options{}, key)
+			go v.f(src)
+			var got [][]msg
+			for m := range b.Channel() {
+				got = append(got, m)
+			}
+			if !reflect.DeepEqual(v.want, got) {
+				tt.Errorf("Channel Recv: want %v, got %v", v.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package chanthrottler
+
+import (
+	"sync"
+	"time"
+)
+
+// Options specifies when a Debouncer emits values.
+type Options struct {
+	// Leading emits the first value immediately if no value was received
+	// during the wait duration before it.
+	Leading bool
+	// NoTrailing does not emit the latest value after the wait duration has
+	// passed without receiving another value.
+	NoTrailing bool
+	// MaxWait is the maximum duration that a value is held back while values
+	// keep arriving, or zero to hold it back indefinitely.
+	MaxWait time.Duration
+}
+
+// accumulator collects the values that are received during a wait duration.
+type accumulator[T, R any] interface {
+	add(v T)
+	// flush returns the collected value and resets the accumulator.
+	flush() R
+}
+
+// debouncer is the loop shared by Debouncer and Batcher, which differ in how
+// the received values are collected.
+type debouncer[T, R any] struct {
+	in       <-chan T
+	out      chan R
+	wait     time.Duration
+	opt      Options
+	acc      accumulator[T, R]
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (d *debouncer[T, R]) send(v R) bool {
+	select {
+	case d.out <- v:
+		return true
+	case <-d.stop:
+		return false
+	}
+}
+
+func drain[T any](ch <-chan T) {
+	for range ch {
+	}
+}
+
+func (d *debouncer[T, R]) run() {
+	defer close(d.done)
+	defer close(d.out)
+	var (
+		hasPending bool
+		waitTimer  *time.Timer
+		waitC      <-chan time.Time
+		maxTimer   *time.Timer
+		maxC       <-chan time.Time
+	)
+	stopTimers := func() {
+		if waitTimer != nil {
+			waitTimer.Stop()
+		}
+		if maxTimer != nil {
+			maxTimer.Stop()
+		}
+	}
+	defer stopTimers()
+	for {
+		select {
+		case v, ok := <-d.in:
+			if !ok {
+				if hasPending && !d.opt.NoTrailing {
+					d.send(d.acc.flush())
+				}
+				return
+			}
+			idle := waitC == nil
+			if waitTimer != nil {
+				waitTimer.Stop()
+			}
+			waitTimer = time.NewTimer(d.wait)
+			waitC = waitTimer.C
+			d.acc.add(v)
+			if idle && d.opt.Leading {
+				if !d.send(d.acc.flush()) {
+					go drain(d.in)
+					return
+				}
+				continue
+			}
+			hasPending = true
+			if d.opt.MaxWait > 0 && maxC == nil {
+				maxTimer = time.NewTimer(d.opt.MaxWait)
+				maxC = maxTimer.C
+			}
+		case <-waitC:
+			waitC, maxC = nil, nil
+			stopTimers()
+			if hasPending && !d.opt.NoTrailing {
+				if !d.send(d.acc.flush()) {
+					go drain(d.in)
+					return
+				}
+			} else {
+				d.acc.flush()
+			}
+			hasPending = false
+		case <-maxC:
+			maxC = nil
+			if hasPending {
+				if !d.send(d.acc.flush()) {
+					go drain(d.in)
+					return
+				}
+			}
+			hasPending = false
+		case <-d.stop:
+			go drain(d.in)
+			return
+		}
+	}
+}
+
+// Channel returns the channel of the debounced values. It is closed when the
+// input channel is closed, after the pending value is emitted, or when the
+// debouncer is stopped.
+func (d *debouncer[T, R]) Channel() <-chan R {
+	return d.out
+}
+
+// Stop stops the debouncer and drops the pending value. The input channel is
+// drained until it is closed, so that its sender does not block.
+func (d *debouncer[T, R]) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
+
+func newDebouncer[T, R any](ch <-chan T, wait time.Duration, opt Options, acc accumulator[T, R]) *debouncer[T, R] {
+	if ch == nil {
+		panic("ch was nil")
+	}
+	d := &debouncer[T, R]{
+		in:   ch,
+		out:  make(chan R),
+		wait: wait,
+		opt:  opt,
+		acc:  acc,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+type latest[T any] struct {
+	v T
+}
+
+func (l *latest[T]) add(v T) {
+	l.v = v
+}
+
+func (l *latest[T]) flush() T {
+	v := l.v
+	var zero T
+	l.v = zero
+	return v
+}
+
+// Debouncer reads values from a channel and only emits the latest value once
+// no value was received for a wait duration.
+type Debouncer[T any] struct {
+	*debouncer[T, T]
+}
+
+// New creates a debouncer that reads the values of ch until it is closed or
+// the debouncer is stopped.
+func New[T any](ch <-chan T, wait time.Duration, opt Options) *Debouncer[T] {
+	return &Debouncer[T]{newDebouncer[T, T](ch, wait, opt, &latest[T]{})}
+}
@@ -0,0 +1,128 @@
+package chanthrottler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func collect(ch <-chan int) []int {
+	var got []int
+	for v := range ch {
+		got = append(got, v)
+	}
+	return got
+}
+
+func TestDebouncer(t *testing.T) {
+	const wait = 32 * time.Millisecond
+	tests := []struct {
+		name string
+		opt  Options
+		f    func(chan int)
+		want []int
+	}{
+		{
+			name: "only push latest value",
+			f: func(c chan int) {
+				for i := 1; i <= 1024; i++ {
+					c <- i
+				}
+				time.Sleep(4 * wait)
+				close(c)
+			},
+			want: []int{1024},
+		},
+		{
+			name: "push pending value before close",
+			f: func(c chan int) {
+				for i := 1; i <= 1024; i++ {
+					c <- i
+				}
+				close(c)
+			},
+			want: []int{1024},
+		},
+		{
+			name: "push latest value of each burst",
+			f: func(c chan int) {
+				c <- 1
+				c <- 2
+				time.Sleep(4 * wait)
+				c <- 3
+				c <- 4
+				close(c)
+			},
+			want: []int{2, 4},
+		},
+		{
+			name: "leading edge",
+			opt:  Options{Leading: true},
+			f: func(c chan int) {
+				c <- 1
+				c <- 2
+				c <- 3
+				time.Sleep(4 * wait)
+				c <- 4
+				time.Sleep(4 * wait)
+				close(c)
+			},
+			want: []int{1, 3, 4},
+		},
+		{
+			name: "leading edge only",
+			opt:  Options{Leading: true, NoTrailing: true},
+			f: func(c chan int) {
+				c <- 1
+				c <- 2
+				c <- 3
+				time.Sleep(4 * wait)
+				c <- 4
+				c <- 5
+				close(c)
+			},
+			want: []int{1, 4},
+		},
+		{
+			name: "max wait",
+			opt:  Options{MaxWait: 5*wait + wait/2},
+			f: func(c chan int) {
+				for i := 1; i <= 8; i++ {
+					c <- i
+					time.Sleep(wait)
+				}
+				close(c)
+			},
+			want: []int{6, 8},
+		},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(tt *testing.T) {
+			src := make(chan int)
+			d := New(src, 2*wait, v.opt)
+			go v.f(src)
+			got := collect(d.Channel())
+			if !reflect.DeepEqual(v.want, got) {
+				tt.Errorf("Channel Recv: want %v, got %v", v.want, got)
+			}
+		})
+	}
+}
+
+func TestDebouncerStop(t *testing.T) {
+	src := make(chan int)
+	d := New(src, time.Hour, Options{})
+	src <- 1
+	d.Stop()
+	if v, ok := <-d.Channel(); ok {
+		t.Errorf("Channel Recv: want closed channel, got %v", v)
+	}
+	// the input is drained after stopping
+	select {
+	case src <- 2:
+	case <-time.After(time.Second):
+		t.Error("send to the input channel blocked after Stop")
+	}
+	d.Stop()
+	close(src)
+}
@@ -5,6 +5,16 @@ import (
 	"reflect"
 
 	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// Backend constants
+const (
+	// BackendV1 is the standalone 'docker-compose' (Python) executable.
+	BackendV1 = Backend(iota)
+	// BackendV2 is the 'docker compose' CLI plugin.
+	BackendV2
 )
 
 // LogLevel constants
@@ -17,31 +27,64 @@ const (
 )
 
 const (
-	composeExecutable = "docker-compose"
-	buildCmd          = "build"
-	upCmd             = "up"
-	tagName           = "compose-option"
+	composeExecutable   = "docker-compose"
+	dockerExecutable    = "docker"
+	composePluginCmd    = "compose"
+	buildCmd            = "build"
+	upCmd               = "up"
+	restartCmd          = "restart"
+	stopCmd             = "stop"
+	downCmd             = "down"
+	logsCmd             = "logs"
+	execCmd             = "exec"
+	cpCmd               = "cp"
+	versionCmd          = "version"
+	tagName             = "compose-option"
+	v2TagName           = "compose-v2-option"
+	unsupportedTagValue = "-"
 )
 
 // LogLevel type for describing level of logging
 type LogLevel string
 
-// CommanderOptions specifies the global flags for docker-compose
+// Rmi values of DownOptions
+const (
+	// RmiAll removes all images used by the services.
+	RmiAll = Rmi("all")
+	// RmiLocal removes the images that don't have a custom tag.
+	RmiLocal = Rmi("local")
+)
+
+// Rmi type for describing which images 'docker-compose down' removes
+type Rmi string
+
+// Backend is the Docker Compose implementation that commands are prepared for.
+type Backend int
+
+// CommanderOptions specifies the global flags for docker-compose.
+//
+// Fields are mapped to flags through the 'compose-option' tag, which may be
+// overridden for the V2 backend with the 'compose-v2-option' tag. A tag value
+// of "-" means that the flag is not supported by the backend.
 type CommanderOptions struct {
 	Files             []string `compose-option:"-f"`
 	ProjectName       string   `compose-option:"-p"`
-	Verbose           bool     `compose-option:"--verbose"`
-	LogLevel          LogLevel `compose-option:"--log-level"`
-	NoAnsi            bool     `compose-option:"--no-ansi"`
+	Verbose           bool     `compose-option:"--verbose" compose-v2-option:"-"`
+	LogLevel          LogLevel `compose-option:"--log-level" compose-v2-option:"-"`
+	NoAnsi            bool     `compose-option:"--no-ansi" compose-v2-option:"--ansi=never"`
+	Ansi              string   `compose-option:"-" compose-v2-option:"--ansi"`
+	Progress          string   `compose-option:"-" compose-v2-option:"--progress"`
 	Version           bool     `compose-option:"--version"`
-	Host              string   `compose-option:"-H"`
-	TLS               bool     `compose-option:"--tls"`
-	TLSCACert         string   `compose-option:"--tlscacert"`
-	TLSCert           string   `compose-option:"--tlscert"`
-	TLSKey            string   `compose-option:"--tlskey"`
-	TLSVerify         bool     `compose-option:"--tlsverify"`
-	SkipHostnameCheck bool     `compose-option:"--skip-hostname-check"`
+	Host              string   `compose-option:"-H" compose-v2-option:"-"`
+	TLS               bool     `compose-option:"--tls" compose-v2-option:"-"`
+	TLSCACert         string   `compose-option:"--tlscacert" compose-v2-option:"-"`
+	TLSCert           string   `compose-option:"--tlscert" compose-v2-option:"-"`
+	TLSKey            string   `compose-option:"--tlskey" compose-v2-option:"-"`
+	TLSVerify         bool     `compose-option:"--tlsverify" compose-v2-option:"-"`
+	SkipHostnameCheck bool     `compose-option:"--skip-hostname-check" compose-v2-option:"-"`
 	ProjectDirectory  string   `compose-option:"--project-directory"`
+	EnvFile           string   `compose-option:"--env-file"`
+	Profiles          []string `compose-option:"--profile"`
 	Compatibility     bool     `compose-option:"--compatibility"`
 }
 
@@ -49,17 +92,19 @@ type CommanderOptions struct {
 type Commander struct {
 	opt     CommanderOptions
 	optArgs []string
+	backend Backend
 }
 
 // BuildOptions are used to specify options (flags) for the 'docker-compose build' command
 type BuildOptions struct {
-	Compress  bool              `compose-option:"--compress"`
-	ForceRM   bool              `compose-option:"--force-rm"`
+	Compress  bool              `compose-option:"--compress" compose-v2-option:"-"`
+	ForceRM   bool              `compose-option:"--force-rm" compose-v2-option:"-"`
 	NoCache   bool              `compose-option:"--no-cache"`
 	Pull      bool              `compose-option:"--pull"`
 	Memory    int               `compose-option:"-m"`
 	BuildArgs map[string]string `compose-option:"--build-arg"`
-	Parallel  bool              `compose-option:"--parallel"`
+	Parallel  bool              `compose-option:"--parallel" compose-v2-option:"-"`
+	Quiet     bool              `compose-option:"-" compose-v2-option:"--quiet"`
 }
 
 // UpOptions are used to specify options (flags) for the 'docker-compose up' command
@@ -80,6 +125,8 @@ type UpOptions struct {
 	RemoveOrphans        bool           `compose-option:"--remove-orphans"`
 	ExitCodeFrom         string         `compose-option:"--exit-code-from"`
 	Scale                map[string]int `compose-option:"--scale"`
+	Wait                 bool           `compose-option:"-" compose-v2-option:"--wait"`
+	WaitTimeout          int            `compose-option:"-" compose-v2-option:"--wait-timeout"`
 }
 
 func taggedValueToArgs(tag string, value interface{}, ignoreZero bool) (args []string) {
@@ -117,7 +164,61 @@ func taggedValueToArgs(tag string, value interface{}, ignoreZero bool) (args []s
 	return args
 }
 
-func optionsToArgs(opt interface{}) (args []string) {
+// RestartOptions are used to specify options (flags) for the 'docker-compose restart' command
+type RestartOptions struct {
+	Timeout int `compose-option:"-t"`
+}
+
+// StopOptions are used to specify options (flags) for the 'docker-compose stop' command
+type StopOptions struct {
+	Timeout int `compose-option:"-t"`
+}
+
+// DownOptions are used to specify options (flags) for the 'docker-compose down' command
+type DownOptions struct {
+	Volumes       bool `compose-option:"-v"`
+	RemoveOrphans bool `compose-option:"--remove-orphans"`
+	Rmi           Rmi  `compose-option:"--rmi"`
+	Timeout       int  `compose-option:"-t"`
+}
+
+// LogsOptions are used to specify options (flags) for the 'docker-compose logs' command
+type LogsOptions struct {
+	Follow     bool   `compose-option:"-f"`
+	Tail       string `compose-option:"--tail"`
+	Timestamps bool   `compose-option:"-t"`
+	NoColor    bool   `compose-option:"--no-color"`
+}
+
+// ExecOptions are used to specify options (flags) for the 'docker-compose exec' command
+type ExecOptions struct {
+	Detach     bool     `compose-option:"-d"`
+	Privileged bool     `compose-option:"--privileged"`
+	User       string   `compose-option:"-u"`
+	NoTTY      bool     `compose-option:"-T"`
+	Index      int      `compose-option:"--index"`
+	Env        []string `compose-option:"-e"`
+	Workdir    string   `compose-option:"-w"`
+}
+
+// CopyOptions are used to specify options (flags) for the 'docker compose cp' command,
+// which is only supported by the V2 backend.
+type CopyOptions struct {
+	Archive    bool `compose-option:"-a"`
+	FollowLink bool `compose-option:"-L"`
+	Index      int  `compose-option:"--index"`
+}
+
+func fieldTag(f reflect.StructField, backend Backend) string {
+	if backend == BackendV2 {
+		if tag, ok := f.Tag.Lookup(v2TagName); ok {
+			return tag
+		}
+	}
+	return f.Tag.Get(tagName)
+}
+
+func optionsToArgs(opt interface{}, backend Backend) (args []string) {
 	if opt == nil {
 		return nil
 	}
@@ -129,8 +230,8 @@ func optionsToArgs(opt interface{}) (args []string) {
 		f := t.Field(i)
 		vi := v.Interface()
 
-		tag := f.Tag.Get(tagName)
-		if tag == "" {
+		tag := fieldTag(f, backend)
+		if tag == "" || tag == unsupportedTagValue {
 			continue
 		}
 
@@ -140,34 +241,123 @@ func optionsToArgs(opt interface{}) (args []string) {
 	return args
 }
 
-// Command returns a 'docker-compose <cmd>' command with the specified arguments.
+// Command returns a 'docker-compose <cmd>' (or 'docker compose <cmd>') command
+// with the specified arguments.
 func (e *Commander) Command(cmd string, arg ...string) *exec.Cmd {
 	var args []string
+	if e.backend == BackendV2 {
+		args = append(args, composePluginCmd)
+	}
 	args = append(args, e.optArgs...)
 	args = append(args, cmd)
 	args = append(args, arg...)
+	if e.backend == BackendV2 {
+		return exec.Command(dockerExecutable, args...)
+	}
 	return exec.Command(composeExecutable, args...)
 }
 
-func (e *Commander) commandWithOptions(cmd string, opt interface{}) *exec.Cmd {
-	return e.Command(cmd, optionsToArgs(opt)...)
+func (e *Commander) commandWithOptions(cmd string, opt interface{}, services []string) *exec.Cmd {
+	return e.Command(cmd, append(optionsToArgs(opt, e.backend), services...)...)
 }
 
 // Build returns a 'docker-compose build' command with the specified options.
-func (e *Commander) Build(opt BuildOptions) *exec.Cmd {
-	return e.commandWithOptions(buildCmd, opt)
+// Only the specified services are built, or all services if none are specified.
+func (e *Commander) Build(opt BuildOptions, services ...string) *exec.Cmd {
+	return e.commandWithOptions(buildCmd, opt, services)
 }
 
 // Up returns a 'docker-compose up' command with the specified options.
-func (e *Commander) Up(opt UpOptions) *exec.Cmd {
-	return e.commandWithOptions(upCmd, opt)
+// Only the specified services are started, or all services if none are specified.
+func (e *Commander) Up(opt UpOptions, services ...string) *exec.Cmd {
+	return e.commandWithOptions(upCmd, opt, services)
+}
+
+// Restart returns a 'docker-compose restart' command with the specified options.
+// Only the specified services are restarted, or all services if none are specified.
+func (e *Commander) Restart(opt RestartOptions, services ...string) *exec.Cmd {
+	return e.commandWithOptions(restartCmd, opt, services)
+}
+
+// Stop returns a 'docker-compose stop' command with the specified options.
+// Only the specified services are stopped, or all services if none are specified.
+func (e *Commander) Stop(opt StopOptions, services ...string) *exec.Cmd {
+	return e.commandWithOptions(stopCmd, opt, services)
+}
+
+// Down returns a 'docker-compose down' command with the specified options, which
+// stops and removes the containers and networks of the project.
+func (e *Commander) Down(opt DownOptions) *exec.Cmd {
+	return e.commandWithOptions(downCmd, opt, nil)
+}
+
+// Logs returns a 'docker-compose logs' command with the specified options.
+// Only the logs of the specified services are shown, or of all services if none are specified.
+func (e *Commander) Logs(opt LogsOptions, services ...string) *exec.Cmd {
+	return e.commandWithOptions(logsCmd, opt, services)
+}
+
+// Exec returns a 'docker-compose exec' command with the specified options, which
+// runs the command in the running container of the service.
+func (e *Commander) Exec(opt ExecOptions, service string, command ...string) *exec.Cmd {
+	return e.commandWithOptions(execCmd, opt, append([]string{service}, command...))
+}
+
+// Copy returns a 'docker compose cp' command with the specified options, which
+// copies src on the host to dst in the container of the service.
+func (e *Commander) Copy(opt CopyOptions, src string, service string, dst string) *exec.Cmd {
+	return e.commandWithOptions(cpCmd, opt, []string{src, service + ":" + dst})
+}
+
+// Backend returns the backend that the commander prepares commands for.
+func (e *Commander) Backend() Backend {
+	return e.backend
+}
+
+// String returns the command used to invoke the backend.
+func (b Backend) String() string {
+	switch b {
+	case BackendV1:
+		return composeExecutable
+	case BackendV2:
+		return dockerExecutable + " " + composePluginCmd
+	default:
+		return fmt.Sprintf("Backend(%d)", int(b))
+	}
+}
+
+var lookPath = exec.LookPath
+
+var runCommand = func(name string, arg ...string) error {
+	return exec.Command(name, arg...).Run()
+}
+
+// DetectBackend detects which Docker Compose implementation is installed. The
+// 'docker compose' plugin is preferred over the standalone 'docker-compose'.
+func DetectBackend() (Backend, error) {
+	if _, err := lookPath(dockerExecutable); err == nil {
+		if err := runCommand(dockerExecutable, composePluginCmd, versionCmd); err == nil {
+			return BackendV2, nil
+		}
+	}
+	if _, err := lookPath(composeExecutable); err == nil {
+		return BackendV1, nil
+	}
+	return 0, errors.New("neither 'docker compose' nor 'docker-compose' is installed")
 }
 
 // NewCommander creates a new commander instance with the specified options (global flags),
-// which will be used when executing commands.
+// which will be used when executing commands with the 'docker-compose' backend.
 func NewCommander(opt CommanderOptions) *Commander {
+	return NewCommanderWithBackend(BackendV1, opt)
+}
+
+// NewCommanderWithBackend creates a new commander instance for the specified backend
+// with the specified options (global flags), which will be used when executing commands.
+func NewCommanderWithBackend(backend Backend, opt CommanderOptions) *Commander {
 	return &Commander{
 		opt:     opt,
-		optArgs: optionsToArgs(opt),
+		optArgs: optionsToArgs(opt, backend),
+		backend: backend,
 	}
 }
@@ -1,6 +1,8 @@
 package dockercompose
 
 import (
+	"errors"
+	"os/exec"
 	"reflect"
 	"testing"
 )
@@ -16,6 +18,7 @@ func TestCommander_Command(t *testing.T) {
 
 	tests := []struct {
 		name        string
+		backend     Backend
 		cargs       cargs
 		args        args
 		wantCmdArgs []string
@@ -37,6 +40,8 @@ func TestCommander_Command(t *testing.T) {
 				TLSVerify:         true,
 				SkipHostnameCheck: true,
 				ProjectDirectory:  "foodir",
+				EnvFile:           "foo.env",
+				Profiles:          []string{"debug", "tools"},
 				Compatibility:     true,
 			}},
 			args: args{"foo-command", nil},
@@ -57,6 +62,9 @@ func TestCommander_Command(t *testing.T) {
 				"--tlsverify",
 				"--skip-hostname-check",
 				"--project-directory", `foodir`,
+				"--env-file", `foo.env`,
+				"--profile", `debug`,
+				"--profile", `tools`,
 				"--compatibility",
 				"foo-command",
 			},
@@ -69,10 +77,50 @@ func TestCommander_Command(t *testing.T) {
 				"docker-compose", "foo-command",
 			},
 		},
+		{
+			name:    "maps the specified flags to the v2 plugin",
+			backend: BackendV2,
+			cargs: cargs{CommanderOptions{
+				Files:             []string{"foo", "bar"},
+				ProjectName:       "baz",
+				Verbose:           true,
+				LogLevel:          LogDebug,
+				NoAnsi:            true,
+				Progress:          "plain",
+				Host:              "boo",
+				TLS:               true,
+				SkipHostnameCheck: true,
+				ProjectDirectory:  "foodir",
+			}},
+			args: args{"foo-command", []string{"foo-arg"}},
+			wantCmdArgs: []string{
+				"docker", "compose",
+				"-f", `foo`,
+				"-f", `bar`,
+				"-p", `baz`,
+				"--ansi=never",
+				"--progress", `plain`,
+				"--project-directory", `foodir`,
+				"foo-command",
+				"foo-arg",
+			},
+		},
+		{
+			name:    "does not pass v2 only flags to v1",
+			backend: BackendV1,
+			cargs: cargs{CommanderOptions{
+				Ansi:     "always",
+				Progress: "plain",
+			}},
+			args: args{"foo-command", nil},
+			wantCmdArgs: []string{
+				"docker-compose", "foo-command",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := NewCommander(tt.cargs.opt)
+			e := NewCommanderWithBackend(tt.backend, tt.cargs.opt)
 			if got := e.Command(tt.args.cmd, tt.args.arg...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
 				t.Errorf("Commander.Command() = %v, want %v", got.Args, tt.wantCmdArgs)
 			}
@@ -82,10 +130,12 @@ func TestCommander_Command(t *testing.T) {
 
 func TestCommander_Up(t *testing.T) {
 	type args struct {
-		opt UpOptions
+		opt      UpOptions
+		services []string
 	}
 	tests := []struct {
 		name        string
+		backend     Backend
 		args        args
 		wantCmdArgs []string
 	}{
@@ -108,7 +158,7 @@ func TestCommander_Up(t *testing.T) {
 				RemoveOrphans:        true,
 				ExitCodeFrom:         "foo",
 				Scale:                map[string]int{"foo": 128},
-			}},
+			}, nil},
 			wantCmdArgs: []string{
 				"docker-compose", "up",
 				"-d",
@@ -131,7 +181,42 @@ func TestCommander_Up(t *testing.T) {
 		},
 		{
 			name: "does not pass the unspecified flags",
-			args: args{UpOptions{}},
+			args: args{UpOptions{}, nil},
+			wantCmdArgs: []string{
+				"docker-compose", "up",
+			},
+		},
+		{
+			name: "passes the specified services after the flags",
+			args: args{UpOptions{NoDeps: true}, []string{"foo", "bar"}},
+			wantCmdArgs: []string{
+				"docker-compose", "up",
+				"--no-deps",
+				"foo", "bar",
+			},
+		},
+		{
+			name:    "passes the v2 flags to the v2 plugin",
+			backend: BackendV2,
+			args: args{UpOptions{
+				Detach:      true,
+				Wait:        true,
+				WaitTimeout: 30,
+			}, []string{"foo"}},
+			wantCmdArgs: []string{
+				"docker", "compose", "up",
+				"-d",
+				"--wait",
+				"--wait-timeout", "30",
+				"foo",
+			},
+		},
+		{
+			name: "does not pass the v2 flags to v1",
+			args: args{UpOptions{
+				Wait:        true,
+				WaitTimeout: 30,
+			}, nil},
 			wantCmdArgs: []string{
 				"docker-compose", "up",
 			},
@@ -139,8 +224,8 @@ func TestCommander_Up(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := NewCommander(CommanderOptions{})
-			if got := e.Up(tt.args.opt); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Up(tt.args.opt, tt.args.services...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
 				t.Errorf("Commander.Up() = %v, want %v", got.Args, tt.wantCmdArgs)
 			}
 		})
@@ -149,10 +234,12 @@ func TestCommander_Up(t *testing.T) {
 
 func TestCommander_Build(t *testing.T) {
 	type args struct {
-		opt BuildOptions
+		opt      BuildOptions
+		services []string
 	}
 	tests := []struct {
 		name        string
+		backend     Backend
 		args        args
 		wantCmdArgs []string
 	}{
@@ -166,7 +253,7 @@ func TestCommander_Build(t *testing.T) {
 				Memory:    64,
 				BuildArgs: map[string]string{"a": "b"},
 				Parallel:  true,
-			}},
+			}, nil},
 			wantCmdArgs: []string{
 				"docker-compose", "build",
 				"--compress",
@@ -180,18 +267,379 @@ func TestCommander_Build(t *testing.T) {
 		},
 		{
 			name: "does not pass the unspecified flags",
-			args: args{BuildOptions{}},
+			args: args{BuildOptions{}, nil},
+			wantCmdArgs: []string{
+				"docker-compose", "build",
+			},
+		},
+		{
+			name: "passes the specified services after the flags",
+			args: args{BuildOptions{NoCache: true}, []string{"foo", "bar"}},
 			wantCmdArgs: []string{
 				"docker-compose", "build",
+				"--no-cache",
+				"foo", "bar",
+			},
+		},
+		{
+			name:    "maps the specified flags to the v2 plugin",
+			backend: BackendV2,
+			args: args{BuildOptions{
+				Compress: true,
+				ForceRM:  true,
+				NoCache:  true,
+				Parallel: true,
+				Quiet:    true,
+			}, nil},
+			wantCmdArgs: []string{
+				"docker", "compose", "build",
+				"--no-cache",
+				"--quiet",
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := NewCommander(CommanderOptions{})
-			if got := e.Build(tt.args.opt); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Build(tt.args.opt, tt.args.services...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
 				t.Errorf("Commander.Build() = %v, want %v", got.Args, tt.wantCmdArgs)
 			}
 		})
 	}
 }
+
+func TestCommander_Restart(t *testing.T) {
+	type args struct {
+		opt      RestartOptions
+		services []string
+	}
+	tests := []struct {
+		name        string
+		backend     Backend
+		args        args
+		wantCmdArgs []string
+	}{
+		{
+			name: "passes the specified flags correctly",
+			args: args{RestartOptions{
+				Timeout: 5,
+			}, []string{"foo"}},
+			wantCmdArgs: []string{
+				"docker-compose", "restart",
+				"-t", "5",
+				"foo",
+			},
+		},
+		{
+			name:    "does not pass the unspecified flags",
+			backend: BackendV2,
+			args:    args{RestartOptions{}, nil},
+			wantCmdArgs: []string{
+				"docker", "compose", "restart",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Restart(tt.args.opt, tt.args.services...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+				t.Errorf("Commander.Restart() = %v, want %v", got.Args, tt.wantCmdArgs)
+			}
+		})
+	}
+}
+
+func TestCommander_Stop(t *testing.T) {
+	type args struct {
+		opt      StopOptions
+		services []string
+	}
+	tests := []struct {
+		name        string
+		backend     Backend
+		args        args
+		wantCmdArgs []string
+	}{
+		{
+			name: "passes the specified flags correctly",
+			args: args{StopOptions{
+				Timeout: 5,
+			}, []string{"foo", "bar"}},
+			wantCmdArgs: []string{
+				"docker-compose", "stop",
+				"-t", "5",
+				"foo", "bar",
+			},
+		},
+		{
+			name:    "does not pass the unspecified flags",
+			backend: BackendV2,
+			args:    args{StopOptions{}, nil},
+			wantCmdArgs: []string{
+				"docker", "compose", "stop",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Stop(tt.args.opt, tt.args.services...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+				t.Errorf("Commander.Stop() = %v, want %v", got.Args, tt.wantCmdArgs)
+			}
+		})
+	}
+}
+
+func TestCommander_Down(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     Backend
+		opt         DownOptions
+		wantCmdArgs []string
+	}{
+		{
+			name: "passes the specified flags correctly",
+			opt: DownOptions{
+				Volumes:       true,
+				RemoveOrphans: true,
+				Rmi:           RmiLocal,
+				Timeout:       5,
+			},
+			wantCmdArgs: []string{
+				"docker-compose", "down",
+				"-v",
+				"--remove-orphans",
+				"--rmi", "local",
+				"-t", "5",
+			},
+		},
+		{
+			name:    "does not pass the unspecified flags",
+			backend: BackendV2,
+			wantCmdArgs: []string{
+				"docker", "compose", "down",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Down(tt.opt); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+				t.Errorf("Commander.Down() = %v, want %v", got.Args, tt.wantCmdArgs)
+			}
+		})
+	}
+}
+
+func TestCommander_Logs(t *testing.T) {
+	type args struct {
+		opt      LogsOptions
+		services []string
+	}
+	tests := []struct {
+		name        string
+		backend     Backend
+		args        args
+		wantCmdArgs []string
+	}{
+		{
+			name: "passes the specified flags correctly",
+			args: args{LogsOptions{
+				Follow:     true,
+				Tail:       "10",
+				Timestamps: true,
+				NoColor:    true,
+			}, []string{"foo", "bar"}},
+			wantCmdArgs: []string{
+				"docker-compose", "logs",
+				"-f",
+				"--tail", "10",
+				"-t",
+				"--no-color",
+				"foo", "bar",
+			},
+		},
+		{
+			name:    "does not pass the unspecified flags",
+			backend: BackendV2,
+			args:    args{LogsOptions{}, nil},
+			wantCmdArgs: []string{
+				"docker", "compose", "logs",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Logs(tt.args.opt, tt.args.services...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+				t.Errorf("Commander.Logs() = %v, want %v", got.Args, tt.wantCmdArgs)
+			}
+		})
+	}
+}
+
+func TestCommander_Exec(t *testing.T) {
+	type args struct {
+		opt     ExecOptions
+		service string
+		command []string
+	}
+	tests := []struct {
+		name        string
+		backend     Backend
+		args        args
+		wantCmdArgs []string
+	}{
+		{
+			name: "passes the specified flags correctly",
+			args: args{ExecOptions{
+				Detach:     true,
+				Privileged: true,
+				User:       "root",
+				NoTTY:      true,
+				Index:      2,
+				Env:        []string{"A=b", "C=d"},
+				Workdir:    "/app",
+			}, "foo", []string{"kill", "-HUP", "1"}},
+			wantCmdArgs: []string{
+				"docker-compose", "exec",
+				"-d",
+				"--privileged",
+				"-u", "root",
+				"-T",
+				"--index", "2",
+				"-e", "A=b",
+				"-e", "C=d",
+				"-w", "/app",
+				"foo",
+				"kill", "-HUP", "1",
+			},
+		},
+		{
+			name:    "does not pass the unspecified flags",
+			backend: BackendV2,
+			args:    args{ExecOptions{}, "foo", []string{"true"}},
+			wantCmdArgs: []string{
+				"docker", "compose", "exec",
+				"foo",
+				"true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Exec(tt.args.opt, tt.args.service, tt.args.command...); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+				t.Errorf("Commander.Exec() = %v, want %v", got.Args, tt.wantCmdArgs)
+			}
+		})
+	}
+}
+
+func TestCommander_Copy(t *testing.T) {
+	type args struct {
+		opt     CopyOptions
+		src     string
+		service string
+		dst     string
+	}
+	tests := []struct {
+		name        string
+		backend     Backend
+		args        args
+		wantCmdArgs []string
+	}{
+		{
+			name:    "passes the specified flags correctly",
+			backend: BackendV2,
+			args: args{CopyOptions{
+				Archive:    true,
+				FollowLink: true,
+				Index:      2,
+			}, "./src/.", "foo", "/app"},
+			wantCmdArgs: []string{
+				"docker", "compose", "cp",
+				"-a",
+				"-L",
+				"--index", "2",
+				"./src/.", "foo:/app",
+			},
+		},
+		{
+			name:    "does not pass the unspecified flags",
+			backend: BackendV2,
+			args:    args{CopyOptions{}, "main.go", "foo", "/app/main.go"},
+			wantCmdArgs: []string{
+				"docker", "compose", "cp",
+				"main.go", "foo:/app/main.go",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewCommanderWithBackend(tt.backend, CommanderOptions{})
+			if got := e.Copy(tt.args.opt, tt.args.src, tt.args.service, tt.args.dst); !reflect.DeepEqual(got.Args, tt.wantCmdArgs) {
+				t.Errorf("Commander.Copy() = %v, want %v", got.Args, tt.wantCmdArgs)
+			}
+		})
+	}
+}
+
+func TestDetectBackend(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []string
+		pluginErr error
+		want      Backend
+		wantErr   bool
+	}{
+		{
+			name:      "prefers the v2 plugin",
+			installed: []string{"docker", "docker-compose"},
+			want:      BackendV2,
+		},
+		{
+			name:      "falls back to v1 when the plugin is missing",
+			installed: []string{"docker", "docker-compose"},
+			pluginErr: errors.New("'compose' is not a docker command"),
+			want:      BackendV1,
+		},
+		{
+			name:      "falls back to v1 without docker",
+			installed: []string{"docker-compose"},
+			want:      BackendV1,
+		},
+		{
+			name:      "fails when nothing is installed",
+			installed: nil,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldLookPath, oldRunCommand := lookPath, runCommand
+			defer func() {
+				lookPath, runCommand = oldLookPath, oldRunCommand
+			}()
+			lookPath = func(file string) (string, error) {
+				for _, v := range tt.installed {
+					if v == file {
+						return "/usr/bin/" + file, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			}
+			runCommand = func(name string, arg ...string) error {
+				return tt.pluginErr
+			}
+			got, err := DetectBackend()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DetectBackend() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DetectBackend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,18 @@ package service
 
 import (
 	"io"
+	"log"
 	"os"
 )
 
 var osOpen = func(name string) (io.Reader, error) {
 	return os.Open(name)
 }
+
+var osStat = os.Stat
+
+var lookupEnv = os.LookupEnv
+
+var logWarningf = func(format string, v ...interface{}) {
+	log.Printf("warning: "+format, v...)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultFileNames are the names of the compose files that are searched for,
+// by order of preference.
+var DefaultFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// ErrNoFile is returned by DiscoverFiles when no compose file is found.
+var ErrNoFile = errors.New("no configuration file provided: not found")
+
+// overrideFileNames returns the names of the override files of a compose file,
+// e.g. 'compose.override.yaml' and 'compose.override.yml' for 'compose.yaml'.
+func overrideFileNames(name string) []string {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	return []string{base + ".override.yaml", base + ".override.yml"}
+}
+
+func fileExists(name string) bool {
+	fi, err := osStat(name)
+	return err == nil && !fi.IsDir()
+}
+
+// envFiles returns the files of the COMPOSE_FILE variable, which are separated
+// by COMPOSE_PATH_SEPARATOR or else by the path list separator of the OS.
+// Relative files are relative to dir.
+func envFiles(dir string) []string {
+	v, ok := lookupEnv("COMPOSE_FILE")
+	if !ok || v == "" {
+		return nil
+	}
+	sep := string(os.PathListSeparator)
+	if s, ok := lookupEnv("COMPOSE_PATH_SEPARATOR"); ok && s != "" {
+		sep = s
+	}
+	var files []string
+	for _, f := range strings.Split(v, sep) {
+		if f == "" {
+			continue
+		}
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(dir, f)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// DiscoverFiles returns the compose files of the project like Docker Compose
+// does when no file is given. The files of the COMPOSE_FILE variable are used
+// if it is set. Otherwise, dir and then its parent directories are searched
+// for the first directory with one of the DefaultFileNames, and the file's
+// override file is included if it exists.
+func DiscoverFiles(dir string) ([]string, error) {
+	if files := envFiles(dir); files != nil {
+		return files, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve directory")
+	}
+	for {
+		var found []string
+		for _, name := range DefaultFileNames {
+			if fileExists(filepath.Join(dir, name)) {
+				found = append(found, name)
+			}
+		}
+		if len(found) > 0 {
+			if len(found) > 1 {
+				logWarningf("found multiple config files with supported names: %s, using %s", strings.Join(found, ", "), found[0])
+			}
+			files := []string{filepath.Join(dir, found[0])}
+			for _, name := range overrideFileNames(found[0]) {
+				if f := filepath.Join(dir, name); fileExists(f) {
+					files = append(files, f)
+					break
+				}
+			}
+			return files, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNoFile
+		}
+		dir = parent
+	}
+}
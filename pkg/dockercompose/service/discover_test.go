@@ -0,0 +1,149 @@
+package service
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type stubFileInfo struct {
+	name string
+	dir  bool
+}
+
+func (f stubFileInfo) Name() string       { return f.name }
+func (f stubFileInfo) Size() int64        { return 0 }
+func (f stubFileInfo) Mode() os.FileMode  { return 0 }
+func (f stubFileInfo) ModTime() time.Time { return time.Time{} }
+func (f stubFileInfo) IsDir() bool        { return f.dir }
+func (f stubFileInfo) Sys() interface{}   { return nil }
+
+// stubStat stats the paths of the map, which are directories if true.
+func stubStat(paths map[string]bool) func(string) (os.FileInfo, error) {
+	return func(name string) (os.FileInfo, error) {
+		dir, ok := paths[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return stubFileInfo{name, dir}, nil
+	}
+}
+
+func TestDiscoverFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		paths    map[string]bool
+		env      map[string]string
+		want     []string
+		wantErr  bool
+		wantWarn bool
+	}{
+		{
+			name:  "compose.yaml",
+			dir:   "/mnt/x",
+			paths: map[string]bool{"/mnt/x/compose.yaml": false},
+			want:  []string{"/mnt/x/compose.yaml"},
+		},
+		{
+			name:  "docker-compose.yml",
+			dir:   "/mnt/x",
+			paths: map[string]bool{"/mnt/x/docker-compose.yml": false},
+			want:  []string{"/mnt/x/docker-compose.yml"},
+		},
+		{
+			name: "preferred name",
+			dir:  "/mnt/x",
+			paths: map[string]bool{
+				"/mnt/x/compose.yml":        false,
+				"/mnt/x/docker-compose.yml": false,
+			},
+			want:     []string{"/mnt/x/compose.yml"},
+			wantWarn: true,
+		},
+		{
+			name: "override",
+			dir:  "/mnt/x",
+			paths: map[string]bool{
+				"/mnt/x/docker-compose.yaml":         false,
+				"/mnt/x/docker-compose.override.yml": false,
+			},
+			want: []string{"/mnt/x/docker-compose.yaml", "/mnt/x/docker-compose.override.yml"},
+		},
+		{
+			name: "override of other file",
+			dir:  "/mnt/x",
+			paths: map[string]bool{
+				"/mnt/x/compose.yaml":                 false,
+				"/mnt/x/docker-compose.override.yaml": false,
+			},
+			want: []string{"/mnt/x/compose.yaml"},
+		},
+		{
+			name: "parent directory",
+			dir:  "/mnt/x/y/z",
+			paths: map[string]bool{
+				"/mnt/compose.yaml":           false,
+				"/mnt/x/compose.override.yml": false,
+				"/mnt/x/compose.yaml":         true,
+			},
+			want: []string{"/mnt/compose.yaml"},
+		},
+		{
+			name:    "not found",
+			dir:     "/mnt/x",
+			paths:   map[string]bool{"/mnt/x/foo.yaml": false},
+			wantErr: true,
+		},
+		{
+			name:  "COMPOSE_FILE",
+			dir:   "/mnt/x",
+			paths: map[string]bool{"/mnt/x/compose.yaml": false},
+			env:   map[string]string{"COMPOSE_FILE": "a.yaml:/mnt/b.yaml"},
+			want:  []string{"/mnt/x/a.yaml", "/mnt/b.yaml"},
+		},
+		{
+			name:  "COMPOSE_PATH_SEPARATOR",
+			dir:   "/mnt/x",
+			paths: map[string]bool{},
+			env: map[string]string{
+				"COMPOSE_FILE":           "a.yaml;b.yaml;",
+				"COMPOSE_PATH_SEPARATOR": ";",
+			},
+			want: []string{"/mnt/x/a.yaml", "/mnt/x/b.yaml"},
+		},
+		{
+			name:  "empty COMPOSE_FILE",
+			dir:   "/mnt/x",
+			paths: map[string]bool{"/mnt/x/compose.yaml": false},
+			env:   map[string]string{"COMPOSE_FILE": ""},
+			want:  []string{"/mnt/x/compose.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldOsStat, oldLogWarningf, oldLookupEnv := osStat, logWarningf, lookupEnv
+			osStat = stubStat(tt.paths)
+			lookupEnv = mapLookup(tt.env)
+			warned := false
+			logWarningf = func(format string, v ...interface{}) {
+				warned = true
+			}
+			defer func() {
+				osStat, logWarningf, lookupEnv = oldOsStat, oldLogWarningf, oldLookupEnv
+			}()
+			got, err := DiscoverFiles(tt.dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DiscoverFiles() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiscoverFiles() = %v, want %v", got, tt.want)
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("DiscoverFiles() warned = %v, wantWarn %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
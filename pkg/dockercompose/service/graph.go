@@ -0,0 +1,79 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TopologicalOrder orders the services of a dependency graph, which maps each
+// service to the services it depends on, so that every service comes after
+// its dependencies. Services without an order between them are sorted by name.
+func TopologicalOrder(deps map[string][]string) ([]string, error) {
+	remaining := make(map[string]int, len(deps))
+	dependents := make(map[string][]string, len(deps))
+	for k, v := range deps {
+		remaining[k] = len(v)
+		for _, d := range v {
+			if _, ok := deps[d]; !ok {
+				return nil, errors.Errorf("service '%s' depends on undefined service '%s'", k, d)
+			}
+			dependents[d] = append(dependents[d], k)
+		}
+	}
+	var ready []string
+	for k, v := range remaining {
+		if v == 0 {
+			ready = append(ready, k)
+		}
+	}
+	order := make([]string, 0, len(deps))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		k := ready[0]
+		ready = ready[1:]
+		order = append(order, k)
+		for _, v := range dependents[k] {
+			remaining[v]--
+			if remaining[v] == 0 {
+				ready = append(ready, v)
+			}
+		}
+	}
+	if len(order) < len(deps) {
+		var cycle []string
+		for k, v := range remaining {
+			if v > 0 {
+				cycle = append(cycle, k)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, errors.Errorf("dependency cycle between services %s", strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// resolveDependents checks the dependencies of the services and sets their
+// dependents.
+func resolveDependents(services map[string]LabelledService) error {
+	deps := make(map[string][]string, len(services))
+	for k, v := range services {
+		deps[k] = v.DependsOn
+	}
+	order, err := TopologicalOrder(deps)
+	if err != nil {
+		return err
+	}
+	dependents := make(map[string][]string, len(services))
+	for _, k := range order {
+		for _, d := range deps[k] {
+			dependents[d] = append(dependents[d], k)
+		}
+	}
+	for k, v := range services {
+		v.Dependents = dependents[k]
+		services[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopologicalOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string][]string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "orders dependencies first",
+			deps: map[string][]string{
+				"web":  {"api", "db"},
+				"api":  {"auth"},
+				"auth": nil,
+				"db":   nil,
+			},
+			want: []string{"auth", "api", "db", "web"},
+		},
+		{
+			name: "undefined dependency",
+			deps: map[string][]string{
+				"api": {"auth"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			deps: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"a"},
+				"d": nil,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TopologicalOrder(tt.deps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TopologicalOrder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopologicalOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LookupFunc returns the value of a variable, and whether it is set.
+type LookupFunc func(name string) (string, bool)
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
+
+// closingBrace returns the index of the brace that closes the braced variable
+// starting at i, skipping the braces of nested variables, or -1.
+func closingBrace(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// expand returns the value of the expression of a braced variable.
+func expand(expr string, lookup LookupFunc) (string, error) {
+	n := 0
+	for n < len(expr) && isNameChar(expr[n]) {
+		n++
+	}
+	name, op := expr[:n], expr[n:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", errors.Errorf("invalid interpolation format for '${%s}'", expr)
+	}
+	v, ok := lookup(name)
+	// operators with a colon also apply when the variable is set but empty
+	unset := !ok
+	if strings.HasPrefix(op, ":") {
+		unset = !ok || v == ""
+		op = op[1:]
+	} else if op == "" {
+		unset = false
+		if !ok {
+			logWarningf("the %s variable is not set, defaulting to a blank string", name)
+		}
+	}
+	if op == "" {
+		if expr[n:] != "" {
+			return "", errors.Errorf("invalid interpolation format for '${%s}'", expr)
+		}
+		return v, nil
+	}
+	arg := op[1:]
+	switch op[0] {
+	case '-':
+		if unset {
+			return Interpolate(arg, lookup)
+		}
+		return v, nil
+	case '?':
+		if unset {
+			msg, err := Interpolate(arg, lookup)
+			if err != nil {
+				return "", err
+			}
+			return "", errors.Errorf("required variable %s is missing a value: %s", name, msg)
+		}
+		return v, nil
+	case '+':
+		if unset {
+			return "", nil
+		}
+		return Interpolate(arg, lookup)
+	default:
+		return "", errors.Errorf("invalid interpolation format for '${%s}'", expr)
+	}
+}
+
+// Interpolate replaces the variables in s like Docker Compose does. It
+// supports $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:?error},
+// ${VAR?error}, ${VAR:+replacement} and ${VAR+replacement}, where the
+// default, error and replacement can contain variables too. '$$' is a
+// literal '$'.
+func Interpolate(s string, lookup LookupFunc) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; {
+		case c == '$':
+			b.WriteByte('$')
+			i++
+		case c == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", errors.Errorf("invalid interpolation format for '%s'", s[i:])
+			}
+			v, err := expand(s[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i = end
+		case isNameStart(c):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			v, err := expand(s[i+1:j], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// interpolateValues interpolates the strings of a decoded yaml document. Keys
+// of mappings are left as they are.
+func interpolateValues(v interface{}, lookup LookupFunc) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return Interpolate(x, lookup)
+	case map[interface{}]interface{}:
+		for k, e := range x {
+			i, err := interpolateValues(e, lookup)
+			if err != nil {
+				return nil, err
+			}
+			x[k] = i
+		}
+	case []interface{}:
+		for k, e := range x {
+			i, err := interpolateValues(e, lookup)
+			if err != nil {
+				return nil, err
+			}
+			x[k] = i
+		}
+	}
+	return v, nil
+}
+
+func unquote(v string, lookup LookupFunc) (string, error) {
+	switch {
+	case len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'':
+		// single quoted values are taken literally
+		return v[1 : len(v)-1], nil
+	case len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"':
+		v = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(v[1 : len(v)-1])
+	default:
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+	}
+	return Interpolate(v, lookup)
+}
+
+// ReadEnvFile reads the variables of an env file, with one 'KEY=VALUE' per
+// line. Values can be quoted, and unless they are single quoted, they can
+// refer to the variables of the lookup and to the previous variables of the file.
+func ReadEnvFile(r io.Reader, lookup LookupFunc) (map[string]string, error) {
+	env := make(map[string]string)
+	envLookup := func(name string) (string, bool) {
+		if v, ok := lookup(name); ok {
+			return v, true
+		}
+		v, ok := env[name]
+		return v, ok
+	}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		kv := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, errors.Errorf("invalid variable on line %d", n)
+		}
+		if len(kv) == 1 {
+			env[key] = ""
+			continue
+		}
+		v, err := unquote(strings.TrimSpace(kv[1]), envLookup)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value of %s on line %d", key, n)
+		}
+		env[key] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read env file")
+	}
+	return env, nil
+}
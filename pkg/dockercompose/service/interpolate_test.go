@@ -0,0 +1,113 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mapLookup(m map[string]string) LookupFunc {
+	return func(name string) (string, bool) {
+		v, ok := m[name]
+		return v, ok
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{
+		"SET":   "value",
+		"EMPTY": "",
+		"NAME":  "SET",
+	}
+	tests := []struct {
+		name     string
+		s        string
+		want     string
+		wantErr  bool
+		wantWarn bool
+	}{
+		{name: "no variables", s: "./api", want: "./api"},
+		{name: "unbraced", s: "$SET/api", want: "value/api"},
+		{name: "braced", s: "${SET}api", want: "valueapi"},
+		{name: "unset", s: "${UNSET}/api", want: "/api", wantWarn: true},
+		{name: "escaped", s: "$$SET $", want: "$SET $"},
+		{name: "default if unset or empty", s: "${EMPTY:-.}/${UNSET:-x}/${SET:-x}", want: "./x/value"},
+		{name: "default if unset", s: "${EMPTY-.}/${UNSET-x}", want: "/x"},
+		{name: "nested default", s: "${UNSET:-${SET}}", want: "value"},
+		{name: "replacement", s: "${SET:+on}${EMPTY:+on}${EMPTY+set}", want: "onset"},
+		{name: "required and set", s: "${SET:?missing}", want: "value"},
+		{name: "required", s: "${EMPTY:?missing}", wantErr: true},
+		{name: "required if unset", s: "${EMPTY?missing}${UNSET?missing}", wantErr: true},
+		{name: "unterminated", s: "${SET", wantErr: true},
+		{name: "invalid name", s: "${1SET}", wantErr: true},
+		{name: "invalid operator", s: "${SET:x}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldLogWarningf := logWarningf
+			warned := false
+			logWarningf = func(format string, v ...interface{}) {
+				warned = true
+			}
+			defer func() {
+				logWarningf = oldLogWarningf
+			}()
+			got, err := Interpolate(tt.s, mapLookup(env))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Interpolate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Interpolate() = %v, want %v", got, tt.want)
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("Interpolate() warned = %v, wantWarn %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "reads variables",
+			file: `
+# comment
+A=1
+export B = two words # comment
+C='${A} #literal'
+D="${A}\n${SHELL_VAR}"
+E
+`,
+			want: map[string]string{
+				"A": "1",
+				"B": "two words",
+				"C": "${A} #literal",
+				"D": "1\nshell",
+				"E": "",
+			},
+		},
+		{
+			name:    "invalid line",
+			file:    "=1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadEnvFile(strings.NewReader(tt.file), mapLookup(map[string]string{"SHELL_VAR": "shell"}))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadEnvFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadEnvFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
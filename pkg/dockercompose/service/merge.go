@@ -0,0 +1,222 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// composeExtends is the service that a service extends, which can be declared
+// either as the name of a service in the same file or as a mapping.
+type composeExtends struct {
+	File    string `yaml:"file,omitempty"`
+	Service string `yaml:"service"`
+}
+
+// UnmarshalYAML unmarshals both the string and the mapping syntax of extends.
+func (e *composeExtends) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var service string
+	if err := unmarshal(&service); err == nil {
+		*e = composeExtends{Service: service}
+		return nil
+	}
+	type plain composeExtends
+	return unmarshal((*plain)(e))
+}
+
+// mergeService merges the override onto the base service. Labels are merged
+// key by key and dependencies are combined, while the other fields of the
+// override replace those of the base.
+func mergeService(base, override composeService) composeService {
+	merged := base
+	if len(override.Labels) > 0 {
+		merged.Labels = make(labels, len(base.Labels)+len(override.Labels))
+		for k, v := range base.Labels {
+			merged.Labels[k] = v
+		}
+		for k, v := range override.Labels {
+			merged.Labels[k] = v
+		}
+	}
+	if override.Build != nil {
+		b := composeBuild{}
+		if base.Build != nil {
+			b = *base.Build
+		}
+		if override.Build.Context != "" {
+			b.Context = override.Build.Context
+		}
+		if override.Build.Dockerfile != "" {
+			b.Dockerfile = override.Build.Dockerfile
+		}
+		merged.Build = &b
+	}
+	if override.Develop != nil {
+		merged.Develop = override.Develop
+	}
+	if len(override.DependsOn) > 0 {
+		merged.DependsOn = nil
+		seen := make(map[string]bool, len(base.DependsOn)+len(override.DependsOn))
+		for _, v := range append(append(dependsOn{}, base.DependsOn...), override.DependsOn...) {
+			if !seen[v] {
+				seen[v] = true
+				merged.DependsOn = append(merged.DependsOn, v)
+			}
+		}
+	}
+	if len(override.Profiles) > 0 {
+		merged.Profiles = override.Profiles
+	}
+	merged.Extends = nil
+	return merged
+}
+
+func rebasePath(p, from, to string) string {
+	if p == "" || filepath.IsAbs(p) || from == to {
+		return p
+	}
+	rel, err := filepath.Rel(to, filepath.Join(from, p))
+	if err != nil {
+		return filepath.Join(from, p)
+	}
+	return rel
+}
+
+// pathLabel is the label of the source directories of a service, which are
+// relative to the directory of the file that declares the label.
+const pathLabel = "docker-compose-watcher.path"
+
+// isPathLabel reports whether the key is the path label or one of its indexed
+// labels ('<pathLabel>.0', '<pathLabel>.1', ...).
+func isPathLabel(key string) bool {
+	if key == pathLabel {
+		return true
+	}
+	i := strings.TrimPrefix(key, pathLabel+".")
+	if i == key || i == "" {
+		return false
+	}
+	for _, c := range i {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// rebasePaths rebases the comma or newline separated paths of a label.
+func rebasePaths(v, from, to string) string {
+	var paths []string
+	for _, p := range strings.FieldsFunc(v, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, rebasePath(p, from, to))
+		}
+	}
+	return strings.Join(paths, ",")
+}
+
+// rebaseService makes the relative paths of a service that is declared in the
+// directory from relative to the directory to.
+func rebaseService(s composeService, from, to string) composeService {
+	if from == to {
+		return s
+	}
+	if len(s.Labels) > 0 {
+		// the labels may be shared with the service that is extended
+		l := make(labels, len(s.Labels))
+		for k, v := range s.Labels {
+			if isPathLabel(k) {
+				v = rebasePaths(v, from, to)
+			}
+			l[k] = v
+		}
+		s.Labels = l
+	}
+	if s.Build != nil && !strings.Contains(s.Build.Context, "://") {
+		b := *s.Build
+		context := b.Context
+		if context == "" {
+			context = "."
+		}
+		b.Context = rebasePath(context, from, to)
+		s.Build = &b
+	}
+	if s.Develop != nil {
+		d := composeDevelop{}
+		for _, v := range s.Develop.Watch {
+			v.Path = rebasePath(v.Path, from, to)
+			d.Watch = append(d.Watch, v)
+		}
+		s.Develop = &d
+	}
+	return s
+}
+
+// extendsResolver resolves the services that services extend. Every file is
+// only read once.
+type extendsResolver struct {
+	files   map[string]*compose
+	visited map[string]bool
+	lookup  LookupFunc
+}
+
+func newExtendsResolver(lookup LookupFunc) *extendsResolver {
+	return &extendsResolver{
+		files:   make(map[string]*compose),
+		visited: make(map[string]bool),
+		lookup:  lookup,
+	}
+}
+
+func (r *extendsResolver) read(file string) (*compose, error) {
+	if c, ok := r.files[file]; ok {
+		return c, nil
+	}
+	c, err := readCompose(file, r.lookup)
+	if err != nil {
+		return nil, err
+	}
+	r.files[file] = c
+	return c, nil
+}
+
+// resolve returns the service of the file with the services that it extends
+// merged into it. The relative paths of the file are relative to dir, and so
+// are those of the returned service.
+func (r *extendsResolver) resolve(file, dir string, c *compose, name string) (composeService, error) {
+	s, ok := c.Services[name]
+	if !ok {
+		return composeService{}, errors.Errorf("service '%s' is not defined in %s", name, file)
+	}
+	if s.Extends == nil {
+		return s, nil
+	}
+	key := file + ":" + name
+	if r.visited[key] {
+		return composeService{}, errors.Errorf("service '%s' of %s extends itself", name, file)
+	}
+	r.visited[key] = true
+	defer delete(r.visited, key)
+	baseFile, baseDir, baseCompose := file, dir, c
+	if s.Extends.File != "" {
+		baseFile = s.Extends.File
+		if !filepath.IsAbs(baseFile) {
+			baseFile = filepath.Join(dir, baseFile)
+		}
+		// the paths of an extended file are relative to its own directory
+		baseDir = filepath.Dir(baseFile)
+		var err error
+		if baseCompose, err = r.read(baseFile); err != nil {
+			return composeService{}, errors.Wrapf(err, "failed to read the file extended by service '%s'", name)
+		}
+	}
+	base, err := r.resolve(baseFile, baseDir, baseCompose, s.Extends.Service)
+	if err != nil {
+		return composeService{}, err
+	}
+	base = rebaseService(base, baseDir, dir)
+	return mergeService(base, s), nil
+}
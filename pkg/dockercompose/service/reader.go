@@ -1,9 +1,14 @@
 package service
 
 import (
+	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -14,14 +19,69 @@ type LabelledService struct {
 	Name      string
 	Directory string
 	Labels    map[string]string
+	Build     Build
+	// Watch are the rules of the develop.watch section.
+	Watch []WatchRule
+	// DependsOn are the services that the service depends on.
+	DependsOn []string
+	// Dependents are the services that depend on the service.
+	Dependents []string
+	// Profiles are the profiles that the service is enabled in, or empty if
+	// the service is always enabled.
+	Profiles []string
+}
+
+// WatchRule is a rule of the develop.watch section of a service.
+type WatchRule struct {
+	// Action is one of 'sync', 'rebuild', 'sync+restart' or 'restart'.
+	Action string `yaml:"action"`
+	// Path is the watched path, relative to the service's Directory.
+	Path string `yaml:"path"`
+	// Target is the path in the container that is synced to.
+	Target string `yaml:"target,omitempty"`
+	// Ignore are patterns of paths to ignore, relative to Path.
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// Build is the build configuration of a service.
+type Build struct {
+	// Context is the build context, relative to the service's Directory,
+	// or empty when the service is not built.
+	Context string
+	// Dockerfile is the Dockerfile relative to Context, or empty for the default.
+	Dockerfile string
+}
+
+// labels are service labels, which can be declared either as a mapping
+// or as a sequence of 'key=value' strings.
+type labels map[string]string
+
+// dependsOn are the dependencies of a service, which can be declared either
+// as a sequence of service names or as a mapping of service names to conditions.
+type dependsOn []string
+
+// composeBuild is the build configuration, which can be declared either as
+// the context path or as a mapping.
+type composeBuild struct {
+	Context    string `yaml:"context,omitempty"`
+	Dockerfile string `yaml:"dockerfile,omitempty"`
+}
+
+type composeDevelop struct {
+	Watch []WatchRule `yaml:"watch,omitempty"`
 }
 
 type composeService struct {
-	Labels map[string]string `yaml:"labels"`
+	Labels    labels          `yaml:"labels"`
+	Build     *composeBuild   `yaml:"build,omitempty"`
+	Develop   *composeDevelop `yaml:"develop,omitempty"`
+	DependsOn dependsOn       `yaml:"depends_on,omitempty"`
+	Extends   *composeExtends `yaml:"extends,omitempty"`
+	Profiles  []string        `yaml:"profiles,omitempty"`
 }
 
 type compose struct {
-	Version  string `yaml:"version"`
+	Version  string `yaml:"version,omitempty"`
 	Services map[string]composeService
 }
 
@@ -32,34 +92,129 @@ type version struct {
 
 // Reader reads from the Docker Compose yaml files
 type Reader struct {
-	files []string
+	files   []string
+	envFile string
+	mtx     sync.Mutex
+	// read are the files that were read by the last ReadLabels
+	read []string
 }
 
 func parseVersion(ver string) (version, error) {
-	// hacky and amazing way to do it
-	floatVer, err := strconv.ParseFloat(ver, 32)
+	parts := strings.SplitN(ver, ".", 2)
+	major, err := strconv.Atoi(parts[0])
 	if err != nil {
-		return version{}, errors.Wrapf(err, "unable to parse version")
+		return version{}, errors.Wrapf(err, "unable to parse major version")
+	}
+	minor := 0
+	if len(parts) > 1 {
+		minor, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return version{}, errors.Wrapf(err, "unable to parse minor version")
+		}
 	}
-	major := int(floatVer)
-	minor := int((floatVer - float64(major)) * 10)
 	return version{
 		Major: major,
 		Minor: minor,
 	}, nil
 }
 
-func validateVersion(ver string) error {
+// checkVersion warns about versions that are not 2.x or 3.x. Files without
+// a version follow the Compose Specification, where the field is obsolete.
+func checkVersion(ver string) {
+	if ver == "" {
+		return
+	}
 	version, err := parseVersion(ver)
 	if err != nil {
-		return err
+		logWarningf("unknown compose file version '%s' (%v), reading it as the Compose Specification", ver, err)
+		return
 	}
-	if version.Major < 1 || version.Major > 3 {
-		return errors.New("invalid version")
+	if version.Major < 2 || version.Major > 3 {
+		logWarningf("unsupported compose file version '%s', reading it as the Compose Specification", ver)
+	}
+}
+
+// UnmarshalYAML unmarshals both the mapping and the sequence syntax of labels.
+func (l *labels) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m map[string]interface{}
+	if err := unmarshal(&m); err == nil {
+		*l = make(labels, len(m))
+		for k, v := range m {
+			if v == nil {
+				(*l)[k] = ""
+				continue
+			}
+			(*l)[k] = fmt.Sprint(v)
+		}
+		return nil
+	}
+	var s []string
+	if err := unmarshal(&s); err != nil {
+		return errors.New("labels must be a mapping or a sequence of 'key=value' strings")
+	}
+	*l = make(labels, len(s))
+	for _, v := range s {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) == 1 {
+			(*l)[kv[0]] = ""
+			continue
+		}
+		(*l)[kv[0]] = kv[1]
 	}
 	return nil
 }
 
+// UnmarshalYAML unmarshals both the sequence and the mapping syntax of depends_on.
+func (d *dependsOn) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s []string
+	if err := unmarshal(&s); err == nil {
+		*d = s
+		return nil
+	}
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return errors.New("depends_on must be a sequence of services or a mapping of services to conditions")
+	}
+	*d = make(dependsOn, 0, len(m))
+	for k := range m {
+		*d = append(*d, k)
+	}
+	sort.Strings(*d)
+	return nil
+}
+
+// UnmarshalYAML unmarshals both the string and the mapping syntax of build.
+func (b *composeBuild) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var context string
+	if err := unmarshal(&context); err == nil {
+		*b = composeBuild{Context: context}
+		return nil
+	}
+	type plain composeBuild
+	return unmarshal((*plain)(b))
+}
+
+func getServiceBuild(service *composeService) Build {
+	if service.Build == nil {
+		return Build{}
+	}
+	context := service.Build.Context
+	if context == "" {
+		context = "."
+	}
+	return Build{
+		Context:    context,
+		Dockerfile: service.Build.Dockerfile,
+	}
+}
+
+func getServiceWatch(service *composeService) []WatchRule {
+	if service.Develop == nil {
+		return nil
+	}
+	return service.Develop.Watch
+}
+
 func getServiceLabels(service *composeService) map[string]string {
 	if service.Labels == nil {
 		return make(map[string]string, 0)
@@ -67,53 +222,167 @@ func getServiceLabels(service *composeService) map[string]string {
 	return service.Labels
 }
 
-func readFromCompose(reader io.Reader) ([]LabelledService, error) {
-	compose := compose{}
+func readFromCompose(reader io.Reader, lookup LookupFunc) (*compose, error) {
+	var raw interface{}
 	decoder := yaml.NewDecoder(reader)
-	if err := decoder.Decode(&compose); err != nil {
+	if err := decoder.Decode(&raw); err != nil {
 		return nil, err
 	}
-	if err := validateVersion(compose.Version); err != nil {
+	raw, err := interpolateValues(raw, lookup)
+	if err != nil {
 		return nil, err
 	}
-	return transformServices(&compose)
+	d, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	compose := compose{}
+	if err := yaml.Unmarshal(d, &compose); err != nil {
+		return nil, err
+	}
+	checkVersion(compose.Version)
+	return &compose, nil
 }
 
-func transformServices(compose *compose) ([]LabelledService, error) {
-	var services []LabelledService
-	for serviceName, service := range compose.Services {
-		services = append(services, LabelledService{
-			Name:   serviceName,
-			Labels: getServiceLabels(&service),
-		})
+// ProjectEnvFile returns the .env file of the project of the Docker Compose
+// files, which is in the directory of the first file.
+func ProjectEnvFile(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(files[0]), ".env")
+}
+
+// readEnv returns the lookup of the variables of the process environment and
+// the env file. The env file is optional unless it was set explicitly.
+func (r *Reader) readEnv() (LookupFunc, error) {
+	file := r.envFile
+	if file == "" {
+		file = ProjectEnvFile(r.files)
+	}
+	if file == "" {
+		return lookupEnv, nil
+	}
+	f, err := osOpen(file)
+	if err != nil {
+		if r.envFile == "" && os.IsNotExist(err) {
+			return lookupEnv, nil
+		}
+		return nil, errors.Wrap(err, "failed to open env file")
+	}
+	if c, ok := f.(io.Closer); ok {
+		defer c.Close()
+	}
+	env, err := ReadEnvFile(f, lookupEnv)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", file)
+	}
+	// the process environment takes precedence over the env file
+	return func(name string) (string, bool) {
+		if v, ok := lookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := env[name]
+		return v, ok
+	}, nil
+}
+
+func readCompose(file string, lookup LookupFunc) (*compose, error) {
+	f, err := osOpen(file)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := f.(io.Closer); ok {
+		defer c.Close()
+	}
+	c, err := readFromCompose(f, lookup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", file)
+	}
+	return c, nil
+}
+
+func transformService(name, directory string, service *composeService) LabelledService {
+	return LabelledService{
+		Name:      name,
+		Directory: directory,
+		Labels:    getServiceLabels(service),
+		Build:     getServiceBuild(service),
+		Watch:     getServiceWatch(service),
+		DependsOn: []string(service.DependsOn),
+		Profiles:  service.Profiles,
 	}
-	return services, nil
 }
 
-// ReadLabels reads all the services and their labels from the Docker Compose files.
+// ReadLabels reads all the services and their labels from the Docker Compose
+// files. A service that is defined in multiple files is merged onto its
+// definitions in the previous files, like Docker Compose does. The relative
+// paths of all files are relative to the project directory, which is the
+// directory of the first file.
 func (r *Reader) ReadLabels() (map[string]LabelledService, error) {
-	services := make(map[string]LabelledService)
+	merged := make(map[string]composeService)
+	var names []string
+	lookup, err := r.readEnv()
+	if err != nil {
+		return nil, err
+	}
+	var dir string
+	if len(r.files) > 0 {
+		dir = filepath.Dir(r.files[0])
+	}
+	resolver := newExtendsResolver(lookup)
+	// the files are recorded even if reading fails, so that fixing them is noticed
+	defer r.setRead(resolver.files)
 	for _, file := range r.files {
-		f, err := osOpen(file)
-		if err != nil {
-			return nil, err
-		}
-		s, err := readFromCompose(f)
+		c, err := resolver.read(file)
 		if err != nil {
 			return nil, err
 		}
-		d := filepath.Dir(file)
-		for _, v := range s {
-			if _, ok := services[v.Name]; ok {
-				return nil, errors.Errorf("service '%s' is defined multiple times", v.Name)
+		for name := range c.Services {
+			s, err := resolver.resolve(file, dir, c, name)
+			if err != nil {
+				return nil, err
 			}
-			v.Directory = d
-			services[v.Name] = v
+			base, ok := merged[name]
+			if !ok {
+				merged[name] = s
+				names = append(names, name)
+				continue
+			}
+			merged[name] = mergeService(base, s)
 		}
 	}
+	services := make(map[string]LabelledService, len(names))
+	for _, name := range names {
+		s := merged[name]
+		services[name] = transformService(name, dir, &s)
+	}
+	if err := resolveDependents(services); err != nil {
+		return nil, err
+	}
 	return services, nil
 }
 
+func (r *Reader) setRead(files map[string]*compose) {
+	read := make([]string, 0, len(files))
+	for k := range files {
+		read = append(read, k)
+	}
+	sort.Strings(read)
+	r.mtx.Lock()
+	r.read = read
+	r.mtx.Unlock()
+}
+
+// Files returns the files that were read by the last ReadLabels, which are the
+// added files and the files of the services that they extend. It is safe to
+// call while the services are read.
+func (r *Reader) Files() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]string(nil), r.read...)
+}
+
 // Add adds a Docker Compose file for the reader to read.
 func (r *Reader) Add(path string) {
 	r.files = append(r.files, path)
@@ -123,3 +392,9 @@ func (r *Reader) Add(path string) {
 func NewReader() *Reader {
 	return &Reader{}
 }
+
+// NewReaderWithEnvFile creates a new Reader that interpolates the variables of
+// the env file instead of the project's .env file.
+func NewReaderWithEnvFile(envFile string) *Reader {
+	return &Reader{envFile: envFile}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v2"
@@ -35,13 +36,20 @@ func composeToYamlReader(a compose) yamlReader {
 	return yamlReader{bytes.NewReader(d), nil}
 }
 
+func stringToYamlReader(s string) yamlReader {
+	return yamlReader{strings.NewReader(s), nil}
+}
+
 func TestReader_Read(t *testing.T) {
 	tests := []struct {
 		name     string
 		files    []string
 		fileOpen func(name string) (io.Reader, error)
+		envFile  string
+		env      map[string]string
 		want     map[string]LabelledService
 		wantErr  bool
+		wantWarn bool
 	}{
 		{
 			name:  "reads specified files",
@@ -98,7 +106,7 @@ func TestReader_Read(t *testing.T) {
 				},
 				"#3": {
 					Name:      "#3",
-					Directory: "/mnt/y",
+					Directory: "/mnt/x",
 					Labels: map[string]string{
 						"name.subkey1": "foo1",
 						"name.subkey2": "foo2",
@@ -106,7 +114,7 @@ func TestReader_Read(t *testing.T) {
 				},
 				"#4": {
 					Name:      "#4",
-					Directory: "/mnt/y",
+					Directory: "/mnt/x",
 					Labels: map[string]string{
 						"name.subkey": "bar",
 					},
@@ -114,49 +122,529 @@ func TestReader_Read(t *testing.T) {
 			},
 		},
 		{
-			name:  "service defined multiple times in separate files",
-			files: []string{"/mnt/x/foo.yaml", "bar.yaml"},
+			name:  "merges services defined in multiple files",
+			files: []string{"/mnt/x/foo.yaml", "/mnt/y/bar.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    build: ./app
+    labels:
+      name.subkey1: foo1
+      name.subkey2: foo2
+    depends_on: ["#2"]
+  "#2":
+    labels:
+      name.subkey: bar
+`),
+				"/mnt/y/bar.yaml": stringToYamlReader(`
+services:
+  "#1":
+    build:
+      dockerfile: Dockerfile.dev
+    labels:
+      - name.subkey2=override
+      - name.subkey3=f3
+    depends_on: ["#3"]
+  "#3": {}
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels: map[string]string{
+						"name.subkey1": "foo1",
+						"name.subkey2": "override",
+						"name.subkey3": "f3",
+					},
+					Build:     Build{Context: "./app", Dockerfile: "Dockerfile.dev"},
+					DependsOn: []string{"#2", "#3"},
+				},
+				"#2": {
+					Name:       "#2",
+					Directory:  "/mnt/x",
+					Labels:     map[string]string{"name.subkey": "bar"},
+					Dependents: []string{"#1"},
+				},
+				"#3": {
+					Name:       "#3",
+					Directory:  "/mnt/x",
+					Labels:     map[string]string{},
+					Dependents: []string{"#1"},
+				},
+			},
+		},
+		{
+			name:  "extends services",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    extends:
+      file: ../base/common.yaml
+      service: base
+    labels:
+      name.own: own
+  "#2":
+    extends: "#1"
+    labels:
+      name.base: overridden
+`),
+				"/mnt/base/common.yaml": stringToYamlReader(`
+services:
+  base:
+    build: ./app
+    labels:
+      name.base: base
+      docker-compose-watcher.path: ./src, ./lib
+      docker-compose-watcher.path.0: /opt/shared
+      docker-compose-watcher.path.1: gen
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels: map[string]string{
+						"name.base":                     "base",
+						"name.own":                      "own",
+						"docker-compose-watcher.path":   "../base/src,../base/lib",
+						"docker-compose-watcher.path.0": "/opt/shared",
+						"docker-compose-watcher.path.1": "../base/gen",
+					},
+					Build: Build{Context: "../base/app"},
+				},
+				"#2": {
+					Name:      "#2",
+					Directory: "/mnt/x",
+					Labels: map[string]string{
+						"name.base":                     "overridden",
+						"name.own":                      "own",
+						"docker-compose-watcher.path":   "../base/src,../base/lib",
+						"docker-compose-watcher.path.0": "/opt/shared",
+						"docker-compose-watcher.path.1": "../base/gen",
+					},
+					Build: Build{Context: "../base/app"},
+				},
+			},
+		},
+		{
+			name:  "extends undefined service",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    extends: base
+`),
+			}),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "extends itself",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    extends: "#2"
+  "#2":
+    extends: "#1"
+`),
+			}),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "file without services",
+			files: []string{"/mnt/x/foo.yaml"},
 			fileOpen: stubOpen(openMap{
 				"/mnt/x/foo.yaml": composeToYamlReader(compose{
 					Version: "3.0",
-					Services: map[string]composeService{
-						"#1": {
-							Labels: map[string]string{
-								"name.subkey1": "foo1",
-								"name.subkey2": "foo2",
-							},
+				}),
+			}),
+			want: map[string]LabelledService{},
+		},
+		{
+			name:  "list-style labels",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    labels:
+      - docker-compose-watcher.path=./src
+      - name.equals=a=b
+      - name.empty
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels: map[string]string{
+						"docker-compose-watcher.path": "./src",
+						"name.equals":                 "a=b",
+						"name.empty":                  "",
+					},
+				},
+			},
+		},
+		{
+			name:  "mapping labels with non-string values",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    labels:
+      name.bool: true
+      name.int: 42
+      name.null:
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels: map[string]string{
+						"name.bool": "true",
+						"name.int":  "42",
+						"name.null": "",
+					},
+				},
+			},
+		},
+		{
+			name:  "build contexts",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    build: ./api
+  "#2":
+    build:
+      context: ./web
+      dockerfile: docker/Dockerfile.dev
+  "#3":
+    build:
+      args:
+        foo: bar
+  "#4":
+    image: foo
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					Build:     Build{Context: "./api"},
+				},
+				"#2": {
+					Name:      "#2",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					Build:     Build{Context: "./web", Dockerfile: "docker/Dockerfile.dev"},
+				},
+				"#3": {
+					Name:      "#3",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					Build:     Build{Context: "."},
+				},
+				"#4": {
+					Name:      "#4",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+				},
+			},
+		},
+		{
+			name:  "develop watch rules",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    develop:
+      watch:
+        - action: sync
+          path: ./web
+          target: /app/web
+          ignore:
+            - node_modules/
+        - action: rebuild
+          path: package.json
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					Watch: []WatchRule{
+						{
+							Action: "sync",
+							Path:   "./web",
+							Target: "/app/web",
+							Ignore: []string{"node_modules/"},
 						},
-						"#2": {
-							Labels: map[string]string{
-								"name.subkey": "bar",
-							},
+						{
+							Action: "rebuild",
+							Path:   "package.json",
 						},
 					},
-				}),
-				"bar.yaml": composeToYamlReader(compose{
-					Version: "3.0",
+				},
+			},
+		},
+		{
+			name:  "dependencies",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  auth: {}
+  db: {}
+  api:
+    depends_on:
+      - auth
+  web:
+    depends_on:
+      db:
+        condition: service_healthy
+      api:
+        condition: service_started
+`),
+			}),
+			want: map[string]LabelledService{
+				"auth": {
+					Name:       "auth",
+					Directory:  "/mnt/x",
+					Labels:     map[string]string{},
+					Dependents: []string{"api"},
+				},
+				"db": {
+					Name:       "db",
+					Directory:  "/mnt/x",
+					Labels:     map[string]string{},
+					Dependents: []string{"web"},
+				},
+				"api": {
+					Name:       "api",
+					Directory:  "/mnt/x",
+					Labels:     map[string]string{},
+					DependsOn:  []string{"auth"},
+					Dependents: []string{"web"},
+				},
+				"web": {
+					Name:      "web",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					DependsOn: []string{"api", "db"},
+				},
+			},
+		},
+		{
+			name:  "undefined dependency",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  api:
+    depends_on: [auth]
+`),
+			}),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "dependency cycle",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  api:
+    depends_on: [web]
+  web:
+    depends_on: [api]
+`),
+			}),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "interpolates variables",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    build: ${SRC_ROOT:-.}/api
+    labels:
+      docker-compose-watcher.path: ${SRC_ROOT:-.}/api
+      price: $$5
+      tag: ${TAG}
+`),
+				"/mnt/x/.env": stringToYamlReader("SRC_ROOT=./src\nTAG=dev\n"),
+			}),
+			env: map[string]string{"TAG": "prod"},
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels: map[string]string{
+						"docker-compose-watcher.path": "./src/api",
+						"price":                       "$5",
+						"tag":                         "prod",
+					},
+					Build: Build{Context: "./src/api"},
+				},
+			},
+		},
+		{
+			name:  "interpolates variables of the env file",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    build: ${SRC_ROOT:?SRC_ROOT must be set}
+`),
+				"/mnt/x/.env":    stringToYamlReader("SRC_ROOT=./default\n"),
+				"/mnt/x/dev.env": stringToYamlReader("SRC_ROOT=./dev\n"),
+			}),
+			envFile: "/mnt/x/dev.env",
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					Build:     Build{Context: "./dev"},
+				},
+			},
+		},
+		{
+			name:  "missing env file",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader("services: {}"),
+			}),
+			envFile: "/mnt/x/dev.env",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "missing required variable",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    build: ${SRC_ROOT:?SRC_ROOT must be set}
+`),
+			}),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "profiles",
+			files: []string{"/mnt/x/foo.yaml", "/mnt/x/bar.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1": {}
+  "#2":
+    profiles: [debug]
+`),
+				"/mnt/x/bar.yaml": stringToYamlReader(`
+services:
+  "#2":
+    profiles: [debug, tools]
+`),
+			}),
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+				},
+				"#2": {
+					Name:      "#2",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{},
+					Profiles:  []string{"debug", "tools"},
+				},
+			},
+		},
+		{
+			name:  "invalid labels",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    labels: foo
+`),
+			}),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:  "file without version",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": composeToYamlReader(compose{
 					Services: map[string]composeService{
 						"#1": {
-							Labels: map[string]string{
-								"name.s1": "f1",
-							},
+							Labels: map[string]string{"name.subkey": "foo"},
 						},
 					},
 				}),
 			}),
-			want:    nil,
-			wantErr: true,
+			want: map[string]LabelledService{
+				"#1": {
+					Name:      "#1",
+					Directory: "/mnt/x",
+					Labels:    map[string]string{"name.subkey": "foo"},
+				},
+			},
 		},
 		{
-			name:  "file without services",
+			name:  "version 2.x",
 			files: []string{"/mnt/x/foo.yaml"},
 			fileOpen: stubOpen(openMap{
 				"/mnt/x/foo.yaml": composeToYamlReader(compose{
-					Version: "3.0",
+					Version: "2.4",
 				}),
 			}),
 			want: map[string]LabelledService{},
 		},
+		{
+			name:  "version 3.x",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": composeToYamlReader(compose{
+					Version: "3.10",
+				}),
+			}),
+			want: map[string]LabelledService{},
+		},
+		{
+			name:  "legacy version",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": composeToYamlReader(compose{
+					Version: "1",
+				}),
+			}),
+			want:     map[string]LabelledService{},
+			wantWarn: true,
+		},
 		{
 			name:  "unsupported version",
 			files: []string{"/mnt/x/foo.yaml"},
@@ -165,18 +653,34 @@ func TestReader_Read(t *testing.T) {
 					Version: "4.0",
 				}),
 			}),
-			want:    nil,
-			wantErr: true,
+			want:     map[string]LabelledService{},
+			wantWarn: true,
+		},
+		{
+			name:  "unparsable version",
+			files: []string{"/mnt/x/foo.yaml"},
+			fileOpen: stubOpen(openMap{
+				"/mnt/x/foo.yaml": composeToYamlReader(compose{
+					Version: "latest",
+				}),
+			}),
+			want:     map[string]LabelledService{},
+			wantWarn: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			oldOsOpen := osOpen
+			oldOsOpen, oldLogWarningf, oldLookupEnv := osOpen, logWarningf, lookupEnv
 			osOpen = tt.fileOpen
+			lookupEnv = mapLookup(tt.env)
+			warned := false
+			logWarningf = func(format string, v ...interface{}) {
+				warned = true
+			}
 			defer func() {
-				osOpen = oldOsOpen
+				osOpen, logWarningf, lookupEnv = oldOsOpen, oldLogWarningf, oldLookupEnv
 			}()
-			r := NewReader()
+			r := NewReaderWithEnvFile(tt.envFile)
 			for _, v := range tt.files {
 				r.Add(v)
 			}
@@ -188,6 +692,57 @@ func TestReader_Read(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Reader.Read() = %v, want %v", got, tt.want)
 			}
+			if warned != tt.wantWarn {
+				t.Errorf("Reader.Read() warned = %v, wantWarn %v", warned, tt.wantWarn)
+			}
 		})
 	}
 }
+
+func TestReader_Files(t *testing.T) {
+	oldOsOpen, oldLookupEnv := osOpen, lookupEnv
+	osOpen = stubOpen(openMap{
+		"/mnt/x/foo.yaml": stringToYamlReader(`
+services:
+  "#1":
+    extends:
+      file: ../base/common.yaml
+      service: base
+`),
+		"/mnt/x/bar.yaml": stringToYamlReader(`
+services:
+  "#2":
+    extends:
+      file: foo.yaml
+      service: "#1"
+`),
+		"/mnt/base/common.yaml": stringToYamlReader(`
+services:
+  base:
+    extends:
+      file: other.yaml
+      service: other
+`),
+		"/mnt/base/other.yaml": stringToYamlReader(`
+services:
+  other: {}
+`),
+	})
+	lookupEnv = mapLookup(nil)
+	defer func() {
+		osOpen, lookupEnv = oldOsOpen, oldLookupEnv
+	}()
+	r := NewReader()
+	r.Add("/mnt/x/foo.yaml")
+	r.Add("/mnt/x/bar.yaml")
+	if got := r.Files(); len(got) != 0 {
+		t.Errorf("Reader.Files() before ReadLabels() = %v, want none", got)
+	}
+	if _, err := r.ReadLabels(); err != nil {
+		t.Fatalf("Reader.ReadLabels() error = %v", err)
+	}
+	want := []string{"/mnt/base/common.yaml", "/mnt/base/other.yaml", "/mnt/x/bar.yaml", "/mnt/x/foo.yaml"}
+	if got := r.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reader.Files() = %v, want %v", got, want)
+	}
+}
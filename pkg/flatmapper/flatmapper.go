@@ -1,9 +1,138 @@
 package flatmapper
 
-import "reflect"
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func splitList(s string) []string {
+	var items []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+// indexedValues returns the values of the keys '<tag>.<index>', ordered by index.
+func indexedValues(tag string, sv reflect.Value) []reflect.Value {
+	type indexed struct {
+		i int
+		v reflect.Value
+	}
+	var values []indexed
+	iter := sv.MapRange()
+	for iter.Next() {
+		k := iter.Key().String()
+		if !strings.HasPrefix(k, tag+".") {
+			continue
+		}
+		i, err := strconv.Atoi(k[len(tag)+1:])
+		if err != nil || i < 0 {
+			continue
+		}
+		values = append(values, indexed{i, iter.Value()})
+	}
+	sort.Slice(values, func(a, b int) bool {
+		return values[a].i < values[b].i
+	})
+	vs := make([]reflect.Value, len(values))
+	for k, v := range values {
+		vs[k] = v.v
+	}
+	return vs
+}
+
+// sliceValue builds a slice of type t from the value of the tag, which is
+// split into a list if it is a string, followed by the indexed values of the tag.
+func sliceValue(tag string, sv reflect.Value, t reflect.Type) (reflect.Value, bool, error) {
+	var items []reflect.Value
+	va := sv.MapIndex(reflect.ValueOf(tag))
+	if va.IsValid() && va.Kind() == reflect.Interface {
+		va = va.Elem()
+	}
+	if va.IsValid() {
+		if va.Kind() != reflect.String {
+			return va, true, nil
+		}
+		for _, v := range splitList(va.String()) {
+			items = append(items, reflect.ValueOf(v))
+		}
+	}
+	items = append(items, indexedValues(tag, sv)...)
+	if len(items) == 0 {
+		return reflect.Value{}, false, nil
+	}
+	s := reflect.MakeSlice(t, 0, len(items))
+	for _, v := range items {
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.String && v.Type() != t.Elem() {
+			cv, err := convertString(v.String(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, false, errors.Wrapf(err, "invalid value '%s' of %s", v.String(), tag)
+			}
+			v = cv
+		}
+		s = reflect.Append(s, v)
+	}
+	return s, true, nil
+}
+
+// convertString converts a string to a value of type t, for fields that are
+// not of the string type themselves.
+func convertString(s string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if t == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(int64(d))
+		return v, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, errors.Errorf("unable to convert string to kind %v", t.Kind())
+	}
+	return v, nil
+}
 
 // MapToStruct maps a flat map (e.g. no struct fields) to a flat struct.
-func MapToStruct(tagKey string, srcStringMap interface{}, dst interface{}) interface{} {
+// String values are converted when the field is a bool, a number, a
+// time.Duration or a named string type. Slice fields are populated from a
+// comma or newline separated list in the tag's value, followed by the values
+// of the indexed tags ('<tag>.0', '<tag>.1', ...).
+func MapToStruct(tagKey string, srcStringMap interface{}, dst interface{}) (interface{}, error) {
 	v := reflect.ValueOf(dst).Elem()
 	t := v.Type()
 	sv := reflect.ValueOf(srcStringMap)
@@ -20,6 +149,16 @@ func MapToStruct(tagKey string, srcStringMap interface{}, dst interface{}) inter
 		if !ok {
 			continue
 		}
+		if f.Type.Kind() == reflect.Slice {
+			va, ok, err := sliceValue(tag, sv, f.Type)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				v.Field(i).Set(va)
+			}
+			continue
+		}
 		va := sv.MapIndex(reflect.ValueOf(tag))
 		if !va.IsValid() {
 			continue
@@ -28,7 +167,14 @@ func MapToStruct(tagKey string, srcStringMap interface{}, dst interface{}) inter
 		if va.Kind() == reflect.Interface {
 			va = va.Elem()
 		}
+		if va.Kind() == reflect.String && va.Type() != f.Type {
+			cv, err := convertString(va.String(), f.Type)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value '%s' of %s", va.String(), tag)
+			}
+			va = cv
+		}
 		v.Field(i).Set(va)
 	}
-	return dst
+	return dst, nil
 }
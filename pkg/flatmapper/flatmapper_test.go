@@ -3,9 +3,11 @@ package flatmapper
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMapToStruct(t *testing.T) {
+	type named string
 	type ts struct {
 		NoTag      string
 		One        string          `mtag:"1"`
@@ -14,6 +16,10 @@ func TestMapToStruct(t *testing.T) {
 		Four       float64         `mtag:"4"`
 		Empty      string          `mtag:""`
 		Impossible struct{ x int } `mtag:"impossible"`
+		Five       []string        `mtag:"5"`
+		Six        []int           `mtag:"6"`
+		Seven      named           `mtag:"7"`
+		Eight      time.Duration   `mtag:"8"`
 	}
 	type args struct {
 		tagKey string
@@ -21,9 +27,10 @@ func TestMapToStruct(t *testing.T) {
 		dst    interface{}
 	}
 	tests := []struct {
-		name string
-		args args
-		want interface{}
+		name    string
+		args    args
+		want    interface{}
+		wantErr bool
 	}{
 		{
 			name: "normal",
@@ -50,10 +57,106 @@ func TestMapToStruct(t *testing.T) {
 				Empty: "empty tag!",
 			},
 		},
+		{
+			name: "converts strings",
+			args: args{
+				tagKey: "mtag",
+				src: map[string]interface{}{
+					"1": "#1",
+					"2": "-2",
+					"3": "true",
+					"4": "2.5",
+					"7": "seven",
+					"8": "1.5s",
+				},
+				dst: &ts{},
+			},
+			want: &ts{
+				One:   "#1",
+				Two:   -2,
+				Three: true,
+				Four:  2.5,
+				Seven: "seven",
+				Eight: 1500 * time.Millisecond,
+			},
+		},
+		{
+			name: "populates slices from lists and indexed tags",
+			args: args{
+				tagKey: "mtag",
+				src: map[string]interface{}{
+					"5":   "a, b\nc,",
+					"5.1": "e",
+					"5.0": "d",
+					"5.x": "ignored",
+					"6.0": "1",
+					"6.2": "3",
+				},
+				dst: &ts{},
+			},
+			want: &ts{
+				Five: []string{"a", "b", "c", "d", "e"},
+				Six:  []int{1, 3},
+			},
+		},
+		{
+			name: "keeps slices",
+			args: args{
+				tagKey: "mtag",
+				src: map[string]interface{}{
+					"5": []string{"a,b"},
+				},
+				dst: &ts{},
+			},
+			want: &ts{
+				Five: []string{"a,b"},
+			},
+		},
+		{
+			name: "invalid slice element",
+			args: args{
+				tagKey: "mtag",
+				src: map[string]interface{}{
+					"6": "1,x",
+				},
+				dst: &ts{},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid duration",
+			args: args{
+				tagKey: "mtag",
+				src: map[string]interface{}{
+					"8": "5",
+				},
+				dst: &ts{},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid string",
+			args: args{
+				tagKey: "mtag",
+				src: map[string]interface{}{
+					"3": "maybe",
+				},
+				dst: &ts{},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := MapToStruct(tt.args.tagKey, tt.args.src, tt.args.dst); !reflect.DeepEqual(got, tt.want) {
+			got, err := MapToStruct(tt.args.tagKey, tt.args.src, tt.args.dst)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MapToStruct() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("MapToStruct() = %v, want %v", got, tt.want)
 			}
 		})
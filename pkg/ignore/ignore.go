@@ -0,0 +1,230 @@
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches slash separated paths, relative to the directory that the
+// patterns apply to, against gitignore-style or .dockerignore patterns.
+type Matcher struct {
+	patterns []pattern
+	docker   bool
+}
+
+func globToRegexp(glob string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				// '**/' matches zero or more directories, a trailing '**' everything
+				if i+2 < len(glob) && glob[i+2] == '/' {
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			j := strings.IndexByte(glob[i+1:], ']')
+			if j < 0 {
+				return "", errors.Errorf("unterminated character class in '%s'", glob)
+			}
+			class := glob[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += j + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+func parseDockerPattern(p string) (pattern, bool, error) {
+	p = strings.TrimSpace(p)
+	if p == "" || strings.HasPrefix(p, "#") {
+		return pattern{}, false, nil
+	}
+	var r pattern
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = strings.TrimSpace(p[1:])
+	}
+	// .dockerignore patterns are always relative to the root of the context
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return pattern{}, false, nil
+	}
+	expr, err := globToRegexp(p)
+	if err != nil {
+		return pattern{}, false, err
+	}
+	r.re, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pattern{}, false, errors.Wrapf(err, "invalid pattern '%s'", p)
+	}
+	return r, true, nil
+}
+
+func parsePattern(p string) (pattern, bool, error) {
+	if strings.HasPrefix(p, "#") {
+		return pattern{}, false, nil
+	}
+	if !strings.HasSuffix(p, `\ `) {
+		p = strings.TrimRight(p, " \t")
+	}
+	if p == "" {
+		return pattern{}, false, nil
+	}
+	var r pattern
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	// patterns without a slash match at any depth, others are anchored
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return pattern{}, false, nil
+	}
+	expr, err := globToRegexp(p)
+	if err != nil {
+		return pattern{}, false, err
+	}
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	r.re, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pattern{}, false, errors.Wrapf(err, "invalid pattern '%s'", p)
+	}
+	return r, true, nil
+}
+
+func (m *Matcher) matchSelf(p string, isDir bool) bool {
+	ignored := false
+	for _, v := range m.patterns {
+		if v.dirOnly && !isDir {
+			continue
+		}
+		if v.re.MatchString(p) {
+			ignored = !v.negate
+		}
+	}
+	return ignored
+}
+
+// matchDocker follows the .dockerignore rules, where a pattern that matches a
+// parent directory matches the path, and later exceptions can re-include it.
+func (m *Matcher) matchDocker(parts []string) bool {
+	ignored := false
+	for _, v := range m.patterns {
+		for i := 1; i <= len(parts); i++ {
+			if v.re.MatchString(strings.Join(parts[:i], "/")) {
+				ignored = !v.negate
+				break
+			}
+		}
+	}
+	return ignored
+}
+
+// Match reports whether the path is ignored. A path is also ignored when
+// any of its parent directories is ignored.
+func (m *Matcher) Match(p string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	p = strings.Trim(path.Clean(p), "/")
+	if p == "." || p == "" {
+		return false
+	}
+	parts := strings.Split(p, "/")
+	if m.docker {
+		return m.matchDocker(parts)
+	}
+	for i := 1; i < len(parts); i++ {
+		if m.matchSelf(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchSelf(p, isDir)
+}
+
+// New creates a matcher from gitignore-style patterns. Blank patterns and
+// patterns starting with '#' are skipped.
+func New(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, v := range patterns {
+		p, ok, err := parsePattern(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, nil
+}
+
+// NewDockerignore creates a matcher from .dockerignore patterns, which are
+// always relative to the root of the build context.
+func NewDockerignore(patterns []string) (*Matcher, error) {
+	m := &Matcher{docker: true}
+	for _, v := range patterns {
+		p, ok, err := parseDockerPattern(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, nil
+}
+
+// ReadPatterns reads the patterns of an ignore file, one per line.
+func ReadPatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		patterns = append(patterns, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read patterns")
+	}
+	return patterns, nil
+}
@@ -0,0 +1,236 @@
+package ignore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	type args struct {
+		path  string
+		isDir bool
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		args     args
+		want     bool
+	}{
+		{
+			name:     "name matches at any depth",
+			patterns: []string{"*.swp"},
+			args:     args{"a/b/.file.swp", false},
+			want:     true,
+		},
+		{
+			name:     "name does not match other names",
+			patterns: []string{"*.swp"},
+			args:     args{"a/b/file.go", false},
+			want:     false,
+		},
+		{
+			name:     "directory pattern matches directories",
+			patterns: []string{"node_modules/"},
+			args:     args{"web/node_modules", true},
+			want:     true,
+		},
+		{
+			name:     "directory pattern does not match files",
+			patterns: []string{"node_modules/"},
+			args:     args{"web/node_modules", false},
+			want:     false,
+		},
+		{
+			name:     "children of ignored directories are ignored",
+			patterns: []string{".git/"},
+			args:     args{".git/objects/ab/cdef", false},
+			want:     true,
+		},
+		{
+			name:     "anchored pattern only matches from the root",
+			patterns: []string{"/build"},
+			args:     args{"src/build", true},
+			want:     false,
+		},
+		{
+			name:     "anchored pattern matches from the root",
+			patterns: []string{"/build"},
+			args:     args{"build", true},
+			want:     true,
+		},
+		{
+			name:     "pattern with slash is anchored",
+			patterns: []string{"docs/*.md"},
+			args:     args{"src/docs/x.md", false},
+			want:     false,
+		},
+		{
+			name:     "double star matches any directories",
+			patterns: []string{"a/**/b"},
+			args:     args{"a/x/y/b", false},
+			want:     true,
+		},
+		{
+			name:     "double star matches zero directories",
+			patterns: []string{"a/**/b"},
+			args:     args{"a/b", false},
+			want:     true,
+		},
+		{
+			name:     "leading double star",
+			patterns: []string{"**/tmp"},
+			args:     args{"x/y/tmp", true},
+			want:     true,
+		},
+		{
+			name:     "trailing double star",
+			patterns: []string{"out/**"},
+			args:     args{"out/x/y", false},
+			want:     true,
+		},
+		{
+			name:     "negation re-includes",
+			patterns: []string{"*.log", "!keep.log"},
+			args:     args{"a/keep.log", false},
+			want:     false,
+		},
+		{
+			name:     "last matching pattern wins",
+			patterns: []string{"!keep.log", "*.log"},
+			args:     args{"a/keep.log", false},
+			want:     true,
+		},
+		{
+			name:     "character classes",
+			patterns: []string{"file[0-9].txt", "x[!a].txt"},
+			args:     args{"file7.txt", false},
+			want:     true,
+		},
+		{
+			name:     "negated character classes",
+			patterns: []string{"x[!a].txt"},
+			args:     args{"xa.txt", false},
+			want:     false,
+		},
+		{
+			name:     "comments and blank lines are skipped",
+			patterns: []string{"# comment", "", "   "},
+			args:     args{"# comment", false},
+			want:     false,
+		},
+		{
+			name:     "escaped characters",
+			patterns: []string{`\#file`, `\!file`},
+			args:     args{"#file", false},
+			want:     true,
+		},
+		{
+			name:     "root is never ignored",
+			patterns: []string{"*"},
+			args:     args{".", true},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.patterns)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := m.Match(tt.args.path, tt.args.isDir); got != tt.want {
+				t.Errorf("Matcher.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New([]string{"[abc"}); err == nil {
+		t.Errorf("New() error = nil, want error for unterminated class")
+	}
+}
+
+func TestReadPatterns(t *testing.T) {
+	got, err := ReadPatterns(strings.NewReader("# comment\n*.swp\n\nnode_modules/\n"))
+	if err != nil {
+		t.Fatalf("ReadPatterns() error = %v", err)
+	}
+	want := []string{"# comment", "*.swp", "", "node_modules/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPatterns() = %v, want %v", got, want)
+	}
+}
+
+func TestMatcher_MatchDockerignore(t *testing.T) {
+	type args struct {
+		path  string
+		isDir bool
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		args     args
+		want     bool
+	}{
+		{
+			name:     "patterns are anchored to the root",
+			patterns: []string{"README.md"},
+			args:     args{"docs/README.md", false},
+			want:     false,
+		},
+		{
+			name:     "pattern matches from the root",
+			patterns: []string{"README.md"},
+			args:     args{"README.md", false},
+			want:     true,
+		},
+		{
+			name:     "children of matched directories are matched",
+			patterns: []string{"test/fixtures"},
+			args:     args{"test/fixtures/a/b.json", false},
+			want:     true,
+		},
+		{
+			name:     "leading and trailing slashes are ignored",
+			patterns: []string{"/test/fixtures/"},
+			args:     args{"test/fixtures/a.json", false},
+			want:     true,
+		},
+		{
+			name:     "double star matches any directories",
+			patterns: []string{"**/*.md"},
+			args:     args{"a/b/c.md", false},
+			want:     true,
+		},
+		{
+			name:     "exceptions re-include files in excluded directories",
+			patterns: []string{"docs", "!docs/keep.md"},
+			args:     args{"docs/keep.md", false},
+			want:     false,
+		},
+		{
+			name:     "other files stay excluded",
+			patterns: []string{"docs", "!docs/keep.md"},
+			args:     args{"docs/other.md", false},
+			want:     true,
+		},
+		{
+			name:     "comments are skipped",
+			patterns: []string{"# README.md"},
+			args:     args{"README.md", false},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewDockerignore(tt.patterns)
+			if err != nil {
+				t.Fatalf("NewDockerignore() error = %v", err)
+			}
+			if got := m.Match(tt.args.path, tt.args.isDir); got != tt.want {
+				t.Errorf("Matcher.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Package procgroup runs commands in their own process group, so that the
+// processes they spawn can be killed together with them.
+package procgroup
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// Start starts the command in a new process group.
+func Start(cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	return cmd.Start()
+}
+
+// Kill kills the process group of a command started with Start.
+func Kill(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return errors.New("process was not started")
+	}
+	return killProcessGroup(cmd)
+}
+
+// Wait waits for a command started with Start to exit. If ctx is done first,
+// the process group is killed and ctx's error is returned.
+func Wait(ctx context.Context, cmd *exec.Cmd) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if err := Kill(cmd); err != nil {
+			return errors.Wrap(err, "failed to kill process group")
+		}
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Run starts the command in a new process group and waits for it to exit. If
+// ctx is done first, the process group is killed and ctx's error is returned.
+func Run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := Start(cmd); err != nil {
+		return err
+	}
+	return Wait(ctx, cmd)
+}
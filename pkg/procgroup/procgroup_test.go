@@ -0,0 +1,45 @@
+//go:build !windows
+
+package procgroup
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		timeout time.Duration
+		wantErr error
+	}{
+		{
+			name:    "waits for the command",
+			args:    []string{"sh", "-c", "exit 0"},
+			timeout: 10 * time.Second,
+		},
+		{
+			name:    "kills the process group",
+			args:    []string{"sh", "-c", "sleep 30 & sleep 30; wait"},
+			timeout: 100 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			err := Run(ctx, exec.Command(tt.args[0], tt.args[1:]...))
+			if err != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if d := time.Since(start); d > 5*time.Second {
+				t.Errorf("Run() took %v", d)
+			}
+		})
+	}
+}
@@ -40,6 +40,12 @@ func (l *Provider) Add(path string) error {
 	return l.watcher.Add(path)
 }
 
+// Watch listens to changes on a file that the values depend on, without
+// adding it to the reader.
+func (l *Provider) Watch(path string) error {
+	return l.watcher.Add(path)
+}
+
 // Sync triggers a synchronization (full re-read) of the provider.
 func (l *Provider) Sync() {
 	l.syncCh <- struct{}{}
@@ -330,6 +330,33 @@ func TestProvider_Add(t *testing.T) {
 	}
 }
 
+func TestProvider_Watch(t *testing.T) {
+	rf := NewReaderDoubleFactoryFunc([]ArgsErr{}, []ArgsErr{}, []ArgsReaderValueErr{})
+	wf := NewWatcherDoubleFactoryFunc([]ArgsErr{{nil}, {fmt.Errorf("foo")}}, []ArgsErr{})
+	ri, _ := rf()
+	wi, _ := wf()
+	r := ri.(*ReaderDouble)
+	w := wi.(*WatcherDouble)
+
+	l, err := New(rf, wf)
+	if err != nil {
+		t.Fatalf("New() error %v", err)
+	}
+	if err := l.Watch("foo"); err != nil {
+		t.Errorf("Provider.Watch() error = %v, wantErr false", err)
+	}
+	if err := l.Watch("bar"); err == nil {
+		t.Errorf("Provider.Watch() error = %v, wantErr true", err)
+	}
+	if len(r.addCalls) != 0 {
+		t.Errorf("Reader.Add() got %v, want no calls", r.addCalls)
+	}
+	want := []ArgsStr{{"foo"}, {"bar"}}
+	if !reflect.DeepEqual(w.addCalls, want) {
+		t.Errorf("Watcher.Add() got %v, want %v", w.addCalls, want)
+	}
+}
+
 func TestProvider(t *testing.T) {
 	type val struct {
 		readerVal  ReaderValue
@@ -0,0 +1,149 @@
+// Package supervisor runs child processes and keeps track of their state.
+package supervisor
+
+import (
+	"docker-compose-watcher/pkg/procgroup"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// States
+const (
+	Starting = State(iota)
+	Running
+	Stopping
+	Exited
+)
+
+// State is the state of a supervised process.
+type State int
+
+func (s State) String() string {
+	switch s {
+	case Starting:
+		return "starting"
+	case Running:
+		return "running"
+	case Stopping:
+		return "stopping"
+	case Exited:
+		return "exited"
+	default:
+		return "unknown"
+	}
+}
+
+// Process is a child process that is reaped as soon as it exits. It runs in
+// its own process group, so that signals of the terminal are not passed to it.
+type Process struct {
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	state    State
+	exitCode int
+	err      error
+	killed   bool
+	done     chan struct{}
+}
+
+func (p *Process) setState(s State) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = s
+}
+
+func (p *Process) wait() {
+	err := p.cmd.Wait()
+	p.mu.Lock()
+	p.state = Exited
+	p.exitCode = p.cmd.ProcessState.ExitCode()
+	p.err = err
+	p.mu.Unlock()
+	close(p.done)
+}
+
+// State returns the state of the process.
+func (p *Process) State() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// ExitCode returns the exit code of the process, or -1 if it has not exited
+// or was terminated by a signal.
+func (p *Process) ExitCode() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != Exited {
+		return -1
+	}
+	return p.exitCode
+}
+
+// Err returns the error that the process exited with, if any.
+func (p *Process) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Killed reports whether the process was killed because it did not stop in time.
+func (p *Process) Killed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.killed
+}
+
+// Done returns a channel that is closed when the process has exited.
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Stop interrupts the process and waits for it to exit. If it has not exited
+// within the timeout, its process group is killed.
+func (p *Process) Stop(timeout time.Duration) error {
+	p.mu.Lock()
+	if p.state == Exited {
+		p.mu.Unlock()
+		return nil
+	}
+	p.state = Stopping
+	p.mu.Unlock()
+	if err := p.cmd.Process.Signal(os.Interrupt); err != nil && err != os.ErrProcessDone {
+		return errors.Wrap(err, "failed to send interrupt signal to process")
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-p.done:
+		return nil
+	case <-t.C:
+	}
+	p.mu.Lock()
+	p.killed = true
+	p.mu.Unlock()
+	if err := procgroup.Kill(p.cmd); err != nil {
+		return errors.Wrap(err, "failed to kill process")
+	}
+	<-p.done
+	return nil
+}
+
+// Start starts the command and supervises its process.
+func Start(cmd *exec.Cmd) (*Process, error) {
+	p := &Process{
+		cmd:      cmd,
+		state:    Starting,
+		exitCode: -1,
+		done:     make(chan struct{}),
+	}
+	if err := procgroup.Start(cmd); err != nil {
+		return nil, err
+	}
+	p.setState(Running)
+	go p.wait()
+	return p, nil
+}
@@ -0,0 +1,70 @@
+//go:build !windows
+
+package supervisor
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		stop         bool
+		wantExitCode int
+		wantKilled   bool
+	}{
+		{
+			name:         "reaps the exit code",
+			script:       "exit 3",
+			wantExitCode: 3,
+		},
+		{
+			name:         "interrupts the process",
+			script:       `trap "exit 0" INT; sleep 5 & wait`,
+			stop:         true,
+			wantExitCode: 0,
+		},
+		{
+			name:         "kills the process if it does not stop",
+			script:       `trap "" INT; sleep 5 & wait; sleep 5`,
+			stop:         true,
+			wantExitCode: -1,
+			wantKilled:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Start(exec.Command("sh", "-c", tt.script))
+			if err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			if got := p.State(); got != Running && got != Exited {
+				t.Errorf("Process.State() = %v, want %v", got, Running)
+			}
+			if tt.stop {
+				// give the shell time to install its trap
+				time.Sleep(100 * time.Millisecond)
+				if err := p.Stop(200 * time.Millisecond); err != nil {
+					t.Fatalf("Process.Stop() error = %v", err)
+				}
+			}
+			select {
+			case <-p.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("process did not exit")
+			}
+			if got := p.State(); got != Exited {
+				t.Errorf("Process.State() = %v, want %v", got, Exited)
+			}
+			if got := p.ExitCode(); got != tt.wantExitCode {
+				t.Errorf("Process.ExitCode() = %v, want %v", got, tt.wantExitCode)
+			}
+			if got := p.Killed(); got != tt.wantKilled {
+				t.Errorf("Process.Killed() = %v, want %v", got, tt.wantKilled)
+			}
+		})
+	}
+}